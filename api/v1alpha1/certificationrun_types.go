@@ -0,0 +1,161 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// CertificationRunSpec defines the desired state of CertificationRun
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="the spec of a CertificationRun cannot be changed, create a new one instead"
+type CertificationRunSpec struct {
+	// OperatorPipelineName is the name of the OperatorPipeline, in the namespace of the run, that installed the
+	// pipeline. Its secrets, service account and workspace settings are used for the run.
+	// +kubebuilder:validation:MinLength=1
+	OperatorPipelineName string `json:"operatorPipelineName"`
+
+	// Pipeline is the name of the Pipeline to run. Defaults to operator-ci-pipeline.
+	// +kubebuilder:default=operator-ci-pipeline
+	// +optional
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// GitRepoURL is the URL of the git repository holding the operator bundle
+	// +kubebuilder:validation:MinLength=1
+	GitRepoURL string `json:"gitRepoURL"`
+
+	// GitRevision is the branch, tag or commit of the repository to certify. Defaults to main.
+	// +kubebuilder:default=main
+	// +optional
+	GitRevision string `json:"gitRevision,omitempty"`
+
+	// BundlePath is the path of the operator bundle in the repository
+	// +kubebuilder:validation:MinLength=1
+	BundlePath string `json:"bundlePath"`
+
+	// OCPVersion is the OpenShift version the bundle is certified for, such as 4.16
+	// +optional
+	OCPVersion string `json:"ocpVersion,omitempty"`
+
+	// Params are passed to the pipeline, overriding the ones the operator sets. Only the params the Pipeline
+	// declares are passed.
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// CertificationRunPhase is the phase of the PipelineRun of a CertificationRun
+type CertificationRunPhase string
+
+const (
+	CertificationRunPending   CertificationRunPhase = "Pending"
+	CertificationRunRunning   CertificationRunPhase = "Running"
+	CertificationRunSucceeded CertificationRunPhase = "Succeeded"
+	CertificationRunFailed    CertificationRunPhase = "Failed"
+)
+
+// CertificationRunStatus defines the observed state of CertificationRun
+type CertificationRunStatus struct {
+	// Phase is the phase of the PipelineRun
+	// +optional
+	Phase CertificationRunPhase `json:"phase,omitempty"`
+
+	// PipelineRunName is the name of the PipelineRun created for the run
+	// +optional
+	PipelineRunName string `json:"pipelineRunName,omitempty"`
+
+	// StartTime is the time the PipelineRun started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the PipelineRun completed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Tasks are the statuses of the TaskRuns of the PipelineRun
+	// +optional
+	Tasks []CertificationTaskStatus `json:"tasks,omitempty"`
+
+	// Results are the results of the PipelineRun
+	// +optional
+	Results []CertificationRunResult `json:"results,omitempty"`
+
+	// Conditions of the run. PipelineRunCreated tells whether the PipelineRun could be created and Succeeded
+	// mirrors the Succeeded condition of the PipelineRun.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// CertificationTaskStatus is the status of a TaskRun of the PipelineRun
+type CertificationTaskStatus struct {
+	// PipelineTaskName is the name of the task in the Pipeline
+	PipelineTaskName string `json:"pipelineTaskName"`
+
+	// TaskRunName is the name of the TaskRun
+	TaskRunName string `json:"taskRunName"`
+
+	// Phase is the phase of the TaskRun
+	Phase CertificationRunPhase `json:"phase"`
+
+	// Reason is the reason of the Succeeded condition of the TaskRun
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the message of the Succeeded condition of the TaskRun
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CertificationRunResult is a result of the PipelineRun
+type CertificationRunResult struct {
+	// Name is the name of the result
+	Name string `json:"name"`
+
+	// Value is the value of the result, in JSON for arrays and objects
+	Value string `json:"value"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="PipelineRun",type=string,JSONPath=`.status.pipelineRunName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CertificationRun is the Schema for the certificationruns API
+type CertificationRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CertificationRunSpec   `json:"spec,omitempty"`
+	Status CertificationRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CertificationRunList contains a list of CertificationRun
+type CertificationRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CertificationRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(GroupVersion, &CertificationRun{}, &CertificationRunList{})
+		metav1.AddToGroupVersion(s, GroupVersion)
+		return nil
+	})
+}
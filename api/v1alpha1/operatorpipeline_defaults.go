@@ -0,0 +1,60 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import "strings"
+
+// Default adds the pipelines enabled by the applyCIPipeline, applyHostedPipeline and applyReleasePipeline fields
+// to the pipelines list, unless they are selected already.
+func (p *OperatorPipeline) Default() {
+	legacy := []struct {
+		file    string
+		enabled bool
+	}{
+		{CIPipelineFile, p.Spec.ApplyCIPipeline},
+		{HostedPipelineFile, p.Spec.ApplyHostedPipeline},
+		{ReleasePipelineFile, p.Spec.ApplyReleasePipeline},
+	}
+
+	for _, pipeline := range legacy {
+		if pipeline.enabled && !p.Spec.selectsPipeline(pipeline.file) {
+			p.Spec.Pipelines = append(p.Spec.Pipelines, PipelineSelection{Name: pipeline.file})
+		}
+	}
+}
+
+// selectsPipeline returns whether a selection already installs the pipeline of the file. Selections may name the
+// file with or without its extension, and a selection aliased to the name of the pipeline would collide with it.
+func (s *OperatorPipelineSpec) selectsPipeline(file string) bool {
+	name := pipelineSelectionName(file)
+	for _, selection := range s.Pipelines {
+		if pipelineSelectionName(selection.Name) == name || selection.Alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pipelineSelectionName returns the selected name without its .yml or .yaml extension.
+func pipelineSelectionName(name string) string {
+	for _, ext := range []string{".yml", ".yaml"} {
+		if trimmed := strings.TrimSuffix(name, ext); trimmed != name {
+			return trimmed
+		}
+	}
+	return name
+}
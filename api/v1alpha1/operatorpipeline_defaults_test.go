@@ -0,0 +1,86 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		name string
+		spec OperatorPipelineSpec
+		want []PipelineSelection
+	}{
+		{
+			name: "nothing enabled",
+			spec: OperatorPipelineSpec{},
+			want: nil,
+		},
+		{
+			name: "legacy fields",
+			spec: OperatorPipelineSpec{ApplyCIPipeline: true, ApplyReleasePipeline: true},
+			want: []PipelineSelection{{Name: CIPipelineFile}, {Name: ReleasePipelineFile}},
+		},
+		{
+			name: "selected by file name",
+			spec: OperatorPipelineSpec{ApplyCIPipeline: true, Pipelines: []PipelineSelection{{Name: "operator-ci-pipeline.yml"}}},
+			want: []PipelineSelection{{Name: "operator-ci-pipeline.yml"}},
+		},
+		{
+			name: "selected without extension",
+			spec: OperatorPipelineSpec{ApplyCIPipeline: true, Pipelines: []PipelineSelection{{Name: "operator-ci-pipeline"}}},
+			want: []PipelineSelection{{Name: "operator-ci-pipeline"}},
+		},
+		{
+			name: "selected with yaml extension",
+			spec: OperatorPipelineSpec{ApplyHostedPipeline: true, Pipelines: []PipelineSelection{{Name: "operator-hosted-pipeline.yaml"}}},
+			want: []PipelineSelection{{Name: "operator-hosted-pipeline.yaml"}},
+		},
+		{
+			name: "selected under an alias",
+			spec: OperatorPipelineSpec{ApplyCIPipeline: true, Pipelines: []PipelineSelection{{Name: "operator-ci-pipeline", Alias: "ci"}}},
+			want: []PipelineSelection{{Name: "operator-ci-pipeline", Alias: "ci"}},
+		},
+		{
+			name: "alias taking the name of the pipeline",
+			spec: OperatorPipelineSpec{ApplyCIPipeline: true, Pipelines: []PipelineSelection{{Name: "custom-ci.yml", Alias: "operator-ci-pipeline"}}},
+			want: []PipelineSelection{{Name: "custom-ci.yml", Alias: "operator-ci-pipeline"}},
+		},
+		{
+			name: "other pipeline selected",
+			spec: OperatorPipelineSpec{ApplyCIPipeline: true, Pipelines: []PipelineSelection{{Name: "operator-release-pipeline"}}},
+			want: []PipelineSelection{{Name: "operator-release-pipeline"}, {Name: CIPipelineFile}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &OperatorPipeline{Spec: tt.spec}
+			p.Default()
+			if !reflect.DeepEqual(p.Spec.Pipelines, tt.want) {
+				t.Fatalf("pipelines = %+v, want %+v", p.Spec.Pipelines, tt.want)
+			}
+
+			// Defaulting twice changes nothing
+			p.Default()
+			if !reflect.DeepEqual(p.Spec.Pipelines, tt.want) {
+				t.Fatalf("pipelines after defaulting twice = %+v, want %+v", p.Spec.Pipelines, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -27,8 +29,119 @@ import (
 // OperatorPipelineSpec defines the desired state of OperatorPipeline
 type OperatorPipelineSpec struct {
 	// OperatorPipelinesRelease is the Operator Pipelines release (version) to install.
+	// The release is looked up as an exact tag, then a branch, then a full or abbreviated commit hash.
+	// A semver constraint such as ~1.4 selects the highest matching tag. Defaults to the main branch.
 	OperatorPipelinesRelease string `json:"operatorPipelinesRelease,omitempty"`
 
+	// UpdatePolicy controls how new commits of the operator-pipelines release are rolled out. Automatic follows the
+	// release as it moves, Manual stays on the commit first installed until the release is changed in the spec, and
+	// Approval only moves to a new commit once the certification.redhat.com/approved-commit annotation is set to it.
+	// Defaults to Automatic.
+	// +kubebuilder:default=Automatic
+	// +kubebuilder:validation:Optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// RollbackToCommit is the full or abbreviated hash of a commit from the status history to roll back to.
+	// The manifests of that commit are re-applied and the pipeline stays on it until the field is cleared.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4,40}$`
+	RollbackToCommit string `json:"rollbackToCommit,omitempty"`
+
+	// OperatorPipelinesRepository is the git URL of the operator-pipelines repository the release is installed from.
+	// Defaults to the upstream operator-pipelines repository on GitHub. HTTPS, SSH and file:// URLs are supported.
+	// +kubebuilder:validation:Optional
+	OperatorPipelinesRepository string `json:"operatorPipelinesRepository,omitempty"`
+
+	// OperatorPipelinesRepositorySecretName is the name of the secret containing the credentials used to access the
+	// operator-pipelines repository. Basic auth (username, password) and SSH (ssh-privatekey and known_hosts)
+	// credentials are supported.
+	// +kubebuilder:validation:Optional
+	OperatorPipelinesRepositorySecretName string `json:"operatorPipelinesRepositorySecretName,omitempty"`
+
+	// InsecureIgnoreHostKey accepts any SSH host key for the operator-pipelines repository when the repository
+	// secret has no known_hosts. The connection can then be intercepted, the GitHostKeyVerified condition warns
+	// about it.
+	// +kubebuilder:validation:Optional
+	InsecureIgnoreHostKey bool `json:"insecureIgnoreHostKey,omitempty"`
+
+	// TrustedKeysConfigMapName is the name of a ConfigMap containing the public keys trusted to sign the
+	// operator-pipelines release. Entries hold armored PGP public keys or SSH public keys in authorized_keys format.
+	// When set, annotated tags must carry a valid tag signature and any other release a valid commit signature.
+	// +kubebuilder:validation:Optional
+	TrustedKeysConfigMapName string `json:"trustedKeysConfigMapName,omitempty"`
+
+	// ManifestSource selects where the pipeline manifests are read from. Defaults to the operator-pipelines git
+	// repository. Disconnected clusters can provide the manifests as a tar archive instead.
+	// +kubebuilder:validation:Optional
+	ManifestSource *ManifestSource `json:"manifestSource,omitempty"`
+
+	// ForceApply takes ownership of the fields of the pipeline manifests that are managed by another field manager.
+	// Without it, objects with conflicting fields are left as they are and reported in the status.
+	// +kubebuilder:validation:Optional
+	ForceApply bool `json:"forceApply,omitempty"`
+
+	// ReconcileMode selects whether the operator changes the cluster. ReportOnly leaves the cluster untouched: the
+	// manifests, service account, workspace template, triggers and image streams are not written, PipelineRuns are
+	// not pruned, the cluster-scoped objects are not deleted with the pipeline, and the status reports how the live
+	// objects differ and what would change. Defaults to Enforce.
+	// +kubebuilder:default=Enforce
+	// +kubebuilder:validation:Optional
+	ReconcileMode ReconcileMode `json:"reconcileMode,omitempty"`
+
+	// Patches are applied, in order, to the manifests of the targeted objects before they are applied to the
+	// cluster. They allow tweaking upstream tasks and pipelines without forking operator-pipelines.
+	// +kubebuilder:validation:Optional
+	Patches []ManifestPatch `json:"patches,omitempty"`
+
+	// TemplateVariables set the variables the Ansible templated manifests are rendered with, such as the images used
+	// by the tasks. They override the defaults of the operator-pipeline role. oc_namespace and service_account are
+	// always the namespace of the OperatorPipeline and the pipeline service account.
+	// +kubebuilder:validation:Optional
+	TemplateVariables map[string]string `json:"templateVariables,omitempty"`
+
+	// ImageMirrors rewrite the image references of the Task steps, step templates and sidecars, and of the
+	// Pipeline param defaults, to pull from a mirror registry. The mapping with the longest matching source wins.
+	// +kubebuilder:validation:Optional
+	ImageMirrors []ImageMirror `json:"imageMirrors,omitempty"`
+
+	// Pipelines selects the pipelines of the release to install, by file name in the pipelines directory of the
+	// release or by Pipeline name. The applyCIPipeline, applyHostedPipeline and applyReleasePipeline fields add
+	// their pipeline to the list.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=name
+	Pipelines []PipelineSelection `json:"pipelines,omitempty"`
+
+	// Tasks adjusts the Tasks installed from the release. By default only the Tasks referenced by the selected
+	// pipelines are installed.
+	// +kubebuilder:validation:Optional
+	Tasks *TaskSelection `json:"tasks,omitempty"`
+
+	// ServiceAccountName is the name of the service account the pipelines run as. The operator creates it when
+	// missing, links the docker registry secret to it and adds the git credential secrets for Tekton's
+	// credential initializer. Defaults to pipeline, the service account created by OpenShift Pipelines.
+	// +kubebuilder:default=pipeline
+	// +kubebuilder:validation:Optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Workspaces sets the volume claim template of the pipeline workspaces. The operator publishes it in the
+	// workspace-template ConfigMap, ready to be passed to tkn pipeline start with volumeClaimTemplateFile. Nothing
+	// is published when it is not set, and a workspace-template ConfigMap the operator did not create is left alone.
+	// +kubebuilder:validation:Optional
+	Workspaces *WorkspaceSettings `json:"workspaces,omitempty"`
+
+	// Triggers starts the CI pipeline on the events of the GitHub repository of the operator bundle. The operator
+	// provisions an EventListener exposed by a Route, to be set as the webhook of the repository. Removing it
+	// tears the listener down.
+	// +kubebuilder:validation:Optional
+	Triggers *TriggersSettings `json:"triggers,omitempty"`
+
+	// Retention prunes the completed PipelineRuns of the pipelines installed by the operator once they are reported.
+	// Their TaskRuns and workspace claims are deleted along with them, their report ConfigMaps are kept until the
+	// OperatorPipeline is deleted. PipelineRuns are kept when it is not set.
+	// +kubebuilder:validation:Optional
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+
 	// GitHubSecretName is the name of the secret containing the GitHub Token that will be used by the pipeline.
 	// +kubebuilder:validation:Optional
 	GitHubSecretName string `json:"gitHubSecretName,omitempty"`
@@ -45,22 +158,238 @@ type OperatorPipelineSpec struct {
 	// The name of the secret containing the github ssh secret expected by the pipeline
 	GithubSSHSecretName string `json:"githubSSHSecretName,omitempty"`
 
-	// ApplyCIPipeline determines whether to install the ci pipeline.
+	// GitCredentialsSecretName is the name of a basic-auth or ssh-auth secret the pipeline clones private
+	// repositories with. The operator annotates it for Tekton's credential initializer and links it to the
+	// pipeline service account.
+	// +kubebuilder:validation:Optional
+	GitCredentialsSecretName string `json:"gitCredentialsSecretName,omitempty"`
+
+	// GitHost is the host the git credential secrets authenticate to, such as a GitHub Enterprise server. Tekton's
+	// credential initializer only hands the credentials to clones from this host. Defaults to github.com.
+	// +kubebuilder:default=github.com
+	// +kubebuilder:validation:Optional
+	GitHost string `json:"gitHost,omitempty"`
+
+	// ApplyCIPipeline determines whether to install the ci pipeline. Prefer selecting it in pipelines.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="CI Pipeline",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
-	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Optional
 	ApplyCIPipeline bool `json:"applyCIPipeline"`
 
-	// ApplyHostedPipeline determines whether to install the hosted pipeline.
+	// ApplyHostedPipeline determines whether to install the hosted pipeline. Prefer selecting it in pipelines.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Hosted Pipeline",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
-	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Optional
 	ApplyHostedPipeline bool `json:"applyHostedPipeline"`
 
-	// ApplyReleasePipeline determines whether to install the release pipeline.
+	// ApplyReleasePipeline determines whether to install the release pipeline. Prefer selecting it in pipelines.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Release Pipeline",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
-	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Optional
 	ApplyReleasePipeline bool `json:"applyReleasePipeline"`
 }
 
+// The pipeline files of the release installed by the applyCIPipeline, applyHostedPipeline and applyReleasePipeline
+// fields
+const (
+	CIPipelineFile      = "operator-ci-pipeline.yml"
+	HostedPipelineFile  = "operator-hosted-pipeline.yml"
+	ReleasePipelineFile = "operator-release-pipeline.yml"
+)
+
+// PipelineSelection selects a pipeline of the operator pipelines release to install
+type PipelineSelection struct {
+	// Name is the file name of the pipeline in the pipelines directory of the release, with or without its
+	// extension, such as operator-ci-pipeline.yml, or the name of the Pipeline
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Alias is the name the Pipeline is installed as, so PipelineRuns can refer to it whatever its name in the
+	// release. The selected file must then contain a single Pipeline.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Alias string `json:"alias,omitempty"`
+}
+
+// TaskSelection adds Tasks of the release to the ones the selected pipelines need, or leaves some of them out.
+// Globs are matched against the Task names, with the syntax of Go's path.Match.
+type TaskSelection struct {
+	// Include installs the Tasks whose names match any of the globs
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude leaves out the Tasks whose names match any of the globs. Excluding a Task a selected pipeline refers
+	// to makes the manifests invalid, since the pipeline could not run without it
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// WorkspaceSettings describes the persistent volume claims created for the pipeline workspaces
+type WorkspaceSettings struct {
+	// StorageClassName is the storage class of the claims. Defaults to the default storage class of the cluster.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Size is the storage requested by the claims. Defaults to 5Gi.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessMode is the access mode of the claims. Defaults to ReadWriteOnce.
+	// +optional
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany;ReadWriteOncePod
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+// TriggerEvent is a GitHub webhook event starting the pipeline
+// +kubebuilder:validation:Enum=push;pull_request
+type TriggerEvent string
+
+const (
+	TriggerEventPush        TriggerEvent = "push"
+	TriggerEventPullRequest TriggerEvent = "pull_request"
+)
+
+// TriggersSettings describes how the GitHub events of the operator bundle repository start the CI pipeline
+type TriggersSettings struct {
+	// Pipeline is the name of the Pipeline started by the events. Defaults to operator-ci-pipeline.
+	// +kubebuilder:default=operator-ci-pipeline
+	// +optional
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// Events are the GitHub events starting the pipeline. Defaults to push and pull_request.
+	// +kubebuilder:default={push,pull_request}
+	// +optional
+	Events []TriggerEvent `json:"events,omitempty"`
+
+	// BundlePath is the path of the operator bundle in the repository
+	// +kubebuilder:validation:MinLength=1
+	BundlePath string `json:"bundlePath"`
+
+	// Params are passed to the pipeline along with the repository, revision and bundle path of the event and the
+	// secrets of the OperatorPipeline. Only the params the Pipeline declares are passed.
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+
+	// WebhookSecretName is the name of the secret holding the webhook secret under the webhook-secret key. The
+	// operator generates it when missing. Defaults to github-webhook-secret.
+	// +optional
+	WebhookSecretName string `json:"webhookSecretName,omitempty"`
+}
+
+// RetentionPolicy selects the completed PipelineRuns to keep. A PipelineRun is pruned when it is beyond the count
+// kept for its outcome or older than the maximum age.
+type RetentionPolicy struct {
+	// KeepSuccessful is the number of the most recent successful PipelineRuns to keep. All are kept when unset.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepSuccessful *int32 `json:"keepSuccessful,omitempty"`
+
+	// KeepFailed is the number of the most recent failed PipelineRuns to keep. All are kept when unset.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepFailed *int32 `json:"keepFailed,omitempty"`
+
+	// MaxAge prunes the PipelineRuns completed longer ago, such as 168h
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// UpdatePolicy controls how new commits of the operator pipelines release are rolled out
+// +kubebuilder:validation:Enum=Manual;Automatic;Approval
+type UpdatePolicy string
+
+const (
+	UpdatePolicyManual    UpdatePolicy = "Manual"
+	UpdatePolicyAutomatic UpdatePolicy = "Automatic"
+	UpdatePolicyApproval  UpdatePolicy = "Approval"
+)
+
+// PatchType is the format of a manifest patch
+// +kubebuilder:validation:Enum=JSON6902;StrategicMerge
+type PatchType string
+
+const (
+	PatchTypeJSON6902       PatchType = "JSON6902"
+	PatchTypeStrategicMerge PatchType = "StrategicMerge"
+)
+
+// ManifestPatch is a patch, stored in a ConfigMap, applied to the manifests of the targeted objects
+type ManifestPatch struct {
+	// Target selects the objects the patch applies to
+	Target PatchTarget `json:"target"`
+
+	// Type is the format of the patch
+	Type PatchType `json:"type"`
+
+	// ConfigMapName is the name of the ConfigMap holding the patch, in YAML or JSON
+	ConfigMapName string `json:"configMapName"`
+
+	// Key is the key of the ConfigMap holding the patch
+	Key string `json:"key"`
+}
+
+// PatchTarget selects the objects of the pipeline manifests a patch applies to
+type PatchTarget struct {
+	// Kind is the kind of the objects, such as Task or Pipeline
+	Kind string `json:"kind"`
+
+	// Name is the name of the object. All the objects of the kind are patched when it is empty.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// ImageMirror maps the image references starting with a source prefix to a mirror
+type ImageMirror struct {
+	// Source is the prefix of the image references to rewrite, such as a registry or a repository. It matches
+	// whole path components, registry.redhat.io matches registry.redhat.io/ubi8 but not registry.redhat.io.example.com
+	// +kubebuilder:validation:MinLength=1
+	Source string `json:"source"`
+
+	// Mirror replaces the source prefix of the matching image references
+	// +kubebuilder:validation:MinLength=1
+	Mirror string `json:"mirror"`
+}
+
+// ReconcileMode selects whether the pipeline manifests are applied or only compared with the cluster
+// +kubebuilder:validation:Enum=Enforce;ReportOnly
+type ReconcileMode string
+
+const (
+	ReconcileModeEnforce    ReconcileMode = "Enforce"
+	ReconcileModeReportOnly ReconcileMode = "ReportOnly"
+)
+
+// ApprovedCommitAnnotation approves the rollout of an operator pipelines commit when the update policy is Approval
+const ApprovedCommitAnnotation = "certification.redhat.com/approved-commit"
+
+// ManifestSourceType is the kind of source the pipeline manifests are read from
+// +kubebuilder:validation:Enum=Git;Archive;ConfigMap;Secret
+type ManifestSourceType string
+
+const (
+	ManifestSourceGit       ManifestSourceType = "Git"
+	ManifestSourceArchive   ManifestSourceType = "Archive"
+	ManifestSourceConfigMap ManifestSourceType = "ConfigMap"
+	ManifestSourceSecret    ManifestSourceType = "Secret"
+)
+
+// ManifestSource describes where the pipeline manifests are read from. Archives are tar files, optionally gzip
+// compressed, of the operator-pipelines repository tree.
+type ManifestSource struct {
+	// Type is the kind of source the manifests are read from
+	// +kubebuilder:default=Git
+	Type ManifestSourceType `json:"type"`
+
+	// Path is the path of the archive mounted into the operator pod, for the Archive type
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Name is the name of the ConfigMap or Secret holding the archive, for the ConfigMap and Secret types
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Key is the key of the ConfigMap or Secret holding the archive. Defaults to manifests.tar.gz.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
 // OperatorPipelineStatus defines the observed state of OperatorPipeline
 type OperatorPipelineStatus struct {
 	// conditions describes the state of the operator's reconciliation functionality.
@@ -74,9 +403,265 @@ type OperatorPipelineStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
-	// PipelinesRepoHash is the hash of the operator-pipelines repo
+	// PipelinesRepoHash is the hash of the operator-pipelines commit the manifests are applied from
 	// +optional
 	PipelinesRepoHash string `json:"pipelinesRepoHash,omitempty"`
+
+	// ManifestArchiveDigest is the sha256 digest of the manifest archive the manifests are read from, when the
+	// manifest source is an archive, ConfigMap or Secret
+	// +optional
+	ManifestArchiveDigest string `json:"manifestArchiveDigest,omitempty"`
+
+	// ResolvedRelease is the git reference the requested operator pipelines release was resolved to
+	// +optional
+	ResolvedRelease *ResolvedRelease `json:"resolvedRelease,omitempty"`
+
+	// PendingUpdate is the newer commit the release resolves to that has not been rolled out because of the
+	// update policy. With the Approval policy it is rolled out once its commit is approved.
+	// +optional
+	PendingUpdate *ResolvedRelease `json:"pendingUpdate,omitempty"`
+
+	// AvailableUpdates lists the tags upstream with a higher version than the installed release tag
+	// +optional
+	AvailableUpdates []string `json:"availableUpdates,omitempty"`
+
+	// History lists the operator-pipelines commits the manifests were applied from, most recent first.
+	// Only the last 10 commits are kept.
+	// +optional
+	History []AppliedRelease `json:"history,omitempty"`
+
+	// Drift lists the objects whose live state differs from the pipeline manifests and was not corrected,
+	// either because the reconcile mode is ReportOnly or because the object could not be applied
+	// +optional
+	Drift []ObjectDrift `json:"drift,omitempty"`
+
+	// Inventory lists every object applied from the pipeline manifests. Objects that drop out of the inventory,
+	// because they are gone from the release or their pipeline is disabled, are deleted.
+	// +optional
+	Inventory []InventoryEntry `json:"inventory,omitempty"`
+
+	// PatchFailures lists the objects a patch could not be applied to. These objects are left as they are in the
+	// cluster until the patch is fixed.
+	// +optional
+	PatchFailures []PatchFailure `json:"patchFailures,omitempty"`
+
+	// RewrittenImages lists the image references of the manifests rewritten by the image mirrors
+	// +optional
+	RewrittenImages []RewrittenImage `json:"rewrittenImages,omitempty"`
+
+	// UnmappedImages lists the image references of the manifests no image mirror matched. Only reported when
+	// image mirrors are set.
+	// +optional
+	UnmappedImages []string `json:"unmappedImages,omitempty"`
+
+	// Pipelines reports the readiness of every selected pipeline
+	// +optional
+	Pipelines []SelectedPipelineStatus `json:"pipelines,omitempty"`
+
+	// ServiceAccount describes the service account the pipelines run as
+	// +optional
+	ServiceAccount *ServiceAccountStatus `json:"serviceAccount,omitempty"`
+
+	// Workspaces describes the volume claim template published for the pipeline workspaces, unset when workspaces
+	// are not set
+	// +optional
+	Workspaces *WorkspacesStatus `json:"workspaces,omitempty"`
+
+	// Triggers describes the webhook starting the pipeline on GitHub events
+	// +optional
+	Triggers *TriggersStatus `json:"triggers,omitempty"`
+
+	// Retention counts the PipelineRuns pruned by the retention policy
+	// +optional
+	Retention *RetentionStatus `json:"retention,omitempty"`
+}
+
+// RetentionStatus counts the PipelineRuns pruned since the retention policy was set
+type RetentionStatus struct {
+	// PrunedSucceeded is the number of successful PipelineRuns pruned
+	PrunedSucceeded int64 `json:"prunedSucceeded"`
+
+	// PrunedFailed is the number of failed PipelineRuns pruned
+	PrunedFailed int64 `json:"prunedFailed"`
+
+	// LastPruneTime is the last time PipelineRuns were pruned
+	// +optional
+	LastPruneTime *metav1.Time `json:"lastPruneTime,omitempty"`
+
+	// PruneCandidates is the number of PipelineRuns the policy would prune, they are kept in ReportOnly mode
+	// +optional
+	PruneCandidates int64 `json:"pruneCandidates,omitempty"`
+}
+
+// TriggersStatus describes the webhook to set on the GitHub repository of the operator bundle
+type TriggersStatus struct {
+	// WebhookURL is the URL of the EventListener, empty until its Route is admitted
+	// +optional
+	WebhookURL string `json:"webhookURL,omitempty"`
+
+	// WebhookSecretName is the name of the secret holding the webhook secret
+	WebhookSecretName string `json:"webhookSecretName"`
+}
+
+// WorkspacesStatus describes the volume claim template published for the pipeline workspaces
+type WorkspacesStatus struct {
+	// ConfigMapName is the name of the ConfigMap holding the volume claim template, empty when it is not
+	// published
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// StorageClassName is the storage class the claims are created with, empty when the cluster has no default
+	// storage class
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// DefaultStorageClass tells whether the storage class is the default storage class of the cluster
+	DefaultStorageClass bool `json:"defaultStorageClass"`
+}
+
+// ServiceAccountStatus describes the service account the pipelines run as and the secrets linked to it
+type ServiceAccountStatus struct {
+	// Name is the name of the service account
+	Name string `json:"name"`
+
+	// ImagePullSecrets are the secrets the operator linked to the service account to pull images with
+	// +optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+
+	// GitCredentials are the secrets the operator annotated and linked for Tekton's credential initializer
+	// +optional
+	GitCredentials []string `json:"gitCredentials,omitempty"`
+}
+
+// SelectedPipelineStatus is the readiness of a pipeline selected in the spec
+type SelectedPipelineStatus struct {
+	// Name is the name of the selection in the spec
+	Name string `json:"name"`
+
+	// PipelineName is the name the Pipeline is installed as
+	// +optional
+	PipelineName string `json:"pipelineName,omitempty"`
+
+	// Ready tells whether the Pipeline is installed
+	Ready bool `json:"ready"`
+
+	// Message describes why the Pipeline is not ready
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RewrittenImage is an image reference of the pipeline manifests rewritten to pull from a mirror
+type RewrittenImage struct {
+	// Original is the image reference in the manifests
+	Original string `json:"original"`
+
+	// Mirrored is the image reference it was rewritten to
+	Mirrored string `json:"mirrored"`
+}
+
+// PatchFailure describes a patch that could not be applied to an object of the pipeline manifests
+type PatchFailure struct {
+	// Kind is the kind of the patched object
+	Kind string `json:"kind"`
+
+	// Name is the name of the patched object
+	Name string `json:"name"`
+
+	// ConfigMapName is the name of the ConfigMap holding the patch
+	ConfigMapName string `json:"configMapName"`
+
+	// Key is the key of the ConfigMap holding the patch
+	Key string `json:"key"`
+
+	// Message describes why the patch could not be applied
+	Message string `json:"message"`
+}
+
+// InventoryEntry identifies an object applied from the pipeline manifests
+type InventoryEntry struct {
+	// APIVersion is the group and version of the object
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the kind of the object
+	Kind string `json:"kind"`
+
+	// Name is the name of the object
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the object, empty for cluster-scoped objects
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DriftType is the way a live object differs from the pipeline manifests
+// +kubebuilder:validation:Enum=Missing;Modified;Unwanted
+type DriftType string
+
+const (
+	// DriftMissing means the object is in the manifests but not in the cluster
+	DriftMissing DriftType = "Missing"
+	// DriftModified means fields of the live object differ from the manifests
+	DriftModified DriftType = "Modified"
+	// DriftUnwanted means the object is in the cluster but the pipeline requests it to be removed
+	DriftUnwanted DriftType = "Unwanted"
+)
+
+// ObjectDrift describes how a live object differs from the pipeline manifests
+type ObjectDrift struct {
+	// Kind is the kind of the object
+	Kind string `json:"kind"`
+
+	// Name is the name of the object
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the object, empty for cluster-scoped objects
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Type is the way the object differs from the manifests
+	Type DriftType `json:"type"`
+
+	// Fields are the paths of the fields set in the manifests that have a different value in the live object
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ReleaseRefType is the kind of git reference an operator pipelines release was resolved to
+// +kubebuilder:validation:Enum=Tag;Branch;Commit
+type ReleaseRefType string
+
+const (
+	ReleaseRefTag    ReleaseRefType = "Tag"
+	ReleaseRefBranch ReleaseRefType = "Branch"
+	ReleaseRefCommit ReleaseRefType = "Commit"
+)
+
+// ResolvedRelease describes the git reference an operator pipelines release was resolved to
+type ResolvedRelease struct {
+	// Type is the kind of git reference the release was resolved to
+	Type ReleaseRefType `json:"type"`
+
+	// Name is the name of the tag or branch, or the commit hash as requested
+	Name string `json:"name"`
+
+	// Commit is the full hash of the commit the release was resolved to
+	Commit string `json:"commit"`
+
+	// Repository is the operator-pipelines repository the release was resolved in
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Release is the release requested in the spec when it was resolved
+	// +optional
+	Release string `json:"release,omitempty"`
+}
+
+// AppliedRelease records an operator pipelines commit the manifests were applied from
+type AppliedRelease struct {
+	ResolvedRelease `json:",inline"`
+
+	// AppliedAt is when the manifests of the commit were applied
+	AppliedAt metav1.Time `json:"appliedAt"`
 }
 
 // +kubebuilder:object:root=true
@@ -25,12 +25,261 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedRelease) DeepCopyInto(out *AppliedRelease) {
+	*out = *in
+	out.ResolvedRelease = in.ResolvedRelease
+	in.AppliedAt.DeepCopyInto(&out.AppliedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedRelease.
+func (in *AppliedRelease) DeepCopy() *AppliedRelease {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedRelease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificationRun) DeepCopyInto(out *CertificationRun) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificationRun.
+func (in *CertificationRun) DeepCopy() *CertificationRun {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificationRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CertificationRun) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificationRunList) DeepCopyInto(out *CertificationRunList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CertificationRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificationRunList.
+func (in *CertificationRunList) DeepCopy() *CertificationRunList {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificationRunList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CertificationRunList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificationRunResult) DeepCopyInto(out *CertificationRunResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificationRunResult.
+func (in *CertificationRunResult) DeepCopy() *CertificationRunResult {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificationRunResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificationRunSpec) DeepCopyInto(out *CertificationRunSpec) {
+	*out = *in
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificationRunSpec.
+func (in *CertificationRunSpec) DeepCopy() *CertificationRunSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificationRunSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificationRunStatus) DeepCopyInto(out *CertificationRunStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = make([]CertificationTaskStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]CertificationRunResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificationRunStatus.
+func (in *CertificationRunStatus) DeepCopy() *CertificationRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificationRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificationTaskStatus) DeepCopyInto(out *CertificationTaskStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificationTaskStatus.
+func (in *CertificationTaskStatus) DeepCopy() *CertificationTaskStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificationTaskStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageMirror) DeepCopyInto(out *ImageMirror) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageMirror.
+func (in *ImageMirror) DeepCopy() *ImageMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryEntry) DeepCopyInto(out *InventoryEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryEntry.
+func (in *InventoryEntry) DeepCopy() *InventoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManifestPatch) DeepCopyInto(out *ManifestPatch) {
+	*out = *in
+	out.Target = in.Target
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManifestPatch.
+func (in *ManifestPatch) DeepCopy() *ManifestPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ManifestPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManifestSource) DeepCopyInto(out *ManifestSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManifestSource.
+func (in *ManifestSource) DeepCopy() *ManifestSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ManifestSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectDrift) DeepCopyInto(out *ObjectDrift) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectDrift.
+func (in *ObjectDrift) DeepCopy() *ObjectDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorPipeline) DeepCopyInto(out *OperatorPipeline) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -87,6 +336,53 @@ func (in *OperatorPipelineList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorPipelineSpec) DeepCopyInto(out *OperatorPipelineSpec) {
 	*out = *in
+	if in.ManifestSource != nil {
+		in, out := &in.ManifestSource, &out.ManifestSource
+		*out = new(ManifestSource)
+		**out = **in
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]ManifestPatch, len(*in))
+		copy(*out, *in)
+	}
+	if in.TemplateVariables != nil {
+		in, out := &in.TemplateVariables, &out.TemplateVariables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ImageMirrors != nil {
+		in, out := &in.ImageMirrors, &out.ImageMirrors
+		*out = make([]ImageMirror, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pipelines != nil {
+		in, out := &in.Pipelines, &out.Pipelines
+		*out = make([]PipelineSelection, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = new(TaskSelection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = new(WorkspaceSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = new(TriggersSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorPipelineSpec.
@@ -109,6 +405,80 @@ func (in *OperatorPipelineStatus) DeepCopyInto(out *OperatorPipelineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ResolvedRelease != nil {
+		in, out := &in.ResolvedRelease, &out.ResolvedRelease
+		*out = new(ResolvedRelease)
+		**out = **in
+	}
+	if in.PendingUpdate != nil {
+		in, out := &in.PendingUpdate, &out.PendingUpdate
+		*out = new(ResolvedRelease)
+		**out = **in
+	}
+	if in.AvailableUpdates != nil {
+		in, out := &in.AvailableUpdates, &out.AvailableUpdates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]AppliedRelease, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]ObjectDrift, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]InventoryEntry, len(*in))
+		copy(*out, *in)
+	}
+	if in.PatchFailures != nil {
+		in, out := &in.PatchFailures, &out.PatchFailures
+		*out = make([]PatchFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.RewrittenImages != nil {
+		in, out := &in.RewrittenImages, &out.RewrittenImages
+		*out = make([]RewrittenImage, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnmappedImages != nil {
+		in, out := &in.UnmappedImages, &out.UnmappedImages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pipelines != nil {
+		in, out := &in.Pipelines, &out.Pipelines
+		*out = make([]SelectedPipelineStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(ServiceAccountStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = new(WorkspacesStatus)
+		**out = **in
+	}
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = new(TriggersStatus)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorPipelineStatus.
@@ -120,3 +490,269 @@ func (in *OperatorPipelineStatus) DeepCopy() *OperatorPipelineStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchFailure) DeepCopyInto(out *PatchFailure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchFailure.
+func (in *PatchFailure) DeepCopy() *PatchFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSelection) DeepCopyInto(out *PipelineSelection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSelection.
+func (in *PipelineSelection) DeepCopy() *PipelineSelection {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSelection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedRelease) DeepCopyInto(out *ResolvedRelease) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedRelease.
+func (in *ResolvedRelease) DeepCopy() *ResolvedRelease {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedRelease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionPolicy) DeepCopyInto(out *RetentionPolicy) {
+	*out = *in
+	if in.KeepSuccessful != nil {
+		in, out := &in.KeepSuccessful, &out.KeepSuccessful
+		*out = new(int32)
+		**out = **in
+	}
+	if in.KeepFailed != nil {
+		in, out := &in.KeepFailed, &out.KeepFailed
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionPolicy.
+func (in *RetentionPolicy) DeepCopy() *RetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionStatus) DeepCopyInto(out *RetentionStatus) {
+	*out = *in
+	if in.LastPruneTime != nil {
+		in, out := &in.LastPruneTime, &out.LastPruneTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionStatus.
+func (in *RetentionStatus) DeepCopy() *RetentionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RewrittenImage) DeepCopyInto(out *RewrittenImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RewrittenImage.
+func (in *RewrittenImage) DeepCopy() *RewrittenImage {
+	if in == nil {
+		return nil
+	}
+	out := new(RewrittenImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelectedPipelineStatus) DeepCopyInto(out *SelectedPipelineStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelectedPipelineStatus.
+func (in *SelectedPipelineStatus) DeepCopy() *SelectedPipelineStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SelectedPipelineStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountStatus) DeepCopyInto(out *ServiceAccountStatus) {
+	*out = *in
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GitCredentials != nil {
+		in, out := &in.GitCredentials, &out.GitCredentials
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountStatus.
+func (in *ServiceAccountStatus) DeepCopy() *ServiceAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskSelection) DeepCopyInto(out *TaskSelection) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSelection.
+func (in *TaskSelection) DeepCopy() *TaskSelection {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskSelection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggersSettings) DeepCopyInto(out *TriggersSettings) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]TriggerEvent, len(*in))
+		copy(*out, *in)
+	}
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggersSettings.
+func (in *TriggersSettings) DeepCopy() *TriggersSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggersSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggersStatus) DeepCopyInto(out *TriggersStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggersStatus.
+func (in *TriggersStatus) DeepCopy() *TriggersStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggersStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceSettings) DeepCopyInto(out *WorkspaceSettings) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceSettings.
+func (in *WorkspaceSettings) DeepCopy() *WorkspaceSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspacesStatus) DeepCopyInto(out *WorkspacesStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspacesStatus.
+func (in *WorkspacesStatus) DeepCopy() *WorkspacesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspacesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/reconcilers"
+
+	tektonpipeline "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// workspaceMountPath is where the pod reading a report from a workspace mounts its claim
+	workspaceMountPath = "/workspace"
+	// workspaceReadRequeue is how often the pod reading a report from a workspace is checked
+	workspaceReadRequeue = 5 * time.Second
+	// workspaceReadTimeout is how long the pod reading a report from a workspace may take, it cannot be scheduled
+	// when the claim is attached to a node it does not fit on
+	workspaceReadTimeout = 10 * time.Minute
+)
+
+// CertificationReportReconciler summarizes the completed PipelineRuns of the pipelines installed by the operator
+// in a report ConfigMap
+type CertificationReportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// APIReader reads the pods and claims, which are not worth caching for the few reports read from a workspace
+	APIReader client.Reader
+	// Pods reads the logs of the pods printing the reports written to a workspace
+	Pods corev1client.PodsGetter
+}
+
+// +kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns;taskruns,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;create;delete
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get
+
+// Reconcile writes the report of a completed PipelineRun to the <PipelineRun>-report ConfigMap. The ConfigMap is
+// owned by the OperatorPipeline that installed the Pipeline rather than by the PipelineRun, so reports outlive the
+// PipelineRuns pruned by the retention policy and go away with the OperatorPipeline. A preflight report the
+// preflight task only wrote to its workspace is read by a pod mounting the claim of the workspace, the report is
+// written once the pod is done, so the PipelineRun and its claim are not pruned before.
+func (r *CertificationReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reqLogger := logf.FromContext(ctx, "Request.Namespace", req.Namespace, "Request.Name", req.Name)
+
+	pr := &tekton.PipelineRun{}
+	if err := r.Get(ctx, req.NamespacedName, pr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !pr.IsDone() {
+		return ctrl.Result{}, nil
+	}
+
+	operatorPipeline, err := r.operatorPipeline(ctx, pr)
+	if err != nil || operatorPipeline == nil {
+		return ctrl.Result{}, err
+	}
+
+	reqLogger.Info("Reporting PipelineRun")
+
+	taskRuns, err := reconcilers.PipelineRunTaskRuns(ctx, r.Client, pr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	report, err := reconcilers.NewCertificationReport(pr, taskRuns)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if report.Preflight == nil {
+		if file := reconcilers.PreflightWorkspaceFile(taskRuns); file != nil {
+			done, err := r.readWorkspacePreflight(ctx, pr, file, report)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !done {
+				return ctrl.Result{RequeueAfter: workspaceReadRequeue}, nil
+			}
+		}
+	}
+
+	data, err := report.ConfigMapData()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: pr.Name + "-report", Namespace: pr.Namespace}}
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, cm, func() error {
+		if cm.Labels == nil {
+			cm.Labels = map[string]string{}
+		}
+		cm.Labels[reconcilers.CertificationReportLabel] = pr.Name
+		if report.Preflight != nil {
+			cm.Labels[reconcilers.PreflightLabel] = reconcilers.CheckFailed
+			if report.Preflight.Passed {
+				cm.Labels[reconcilers.PreflightLabel] = reconcilers.CheckPassed
+			}
+		}
+		cm.Data = data
+		// Reports written before they were owned by the OperatorPipeline lose their PipelineRun owner
+		owned, err := controllerutil.HasOwnerReference(cm.OwnerReferences, pr, r.Scheme)
+		if err != nil {
+			return err
+		}
+		if owned {
+			if err := controllerutil.RemoveOwnerReference(pr, cm, r.Scheme); err != nil {
+				return err
+			}
+		}
+		// Not a controller reference, the OperatorPipeline controller does not need to hear of every report
+		return controllerutil.SetOwnerReference(operatorPipeline, cm, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info(fmt.Sprintf("certification report %s %s", cm.Name, result))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// readWorkspacePreflight sets the preflight report of the report from the file the preflight task wrote to its
+// workspace. A file already read for the existing report is not read again. Otherwise a pod mounting the claim of
+// the workspace prints the file, it returns false until the pod is done.
+func (r *CertificationReportReconciler) readWorkspacePreflight(ctx context.Context, pr *tekton.PipelineRun, file *reconcilers.WorkspaceFile, report *reconcilers.CertificationReport) (bool, error) {
+	reqLogger := logf.FromContext(ctx)
+
+	existing := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name + "-report"}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil && report.SetExistingPreflight(existing, file) {
+		return true, nil
+	}
+
+	report.PreflightFile = file.Path
+	err = r.APIReader.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: file.ClaimName}, &corev1.PersistentVolumeClaim{})
+	if errors.IsNotFound(err) {
+		report.PreflightNotice = fmt.Sprintf("Claim %s holding the preflight report of task %s no longer exists", file.ClaimName, file.Task)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	pod := &corev1.Pod{}
+	err = r.APIReader.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name + "-preflight-report"}, pod)
+	if errors.IsNotFound(err) {
+		pod = workspaceFilePod(pr, file)
+		if err := controllerutil.SetControllerReference(pr, pod, r.Scheme); err != nil {
+			return false, err
+		}
+		reqLogger.Info(fmt.Sprintf("reading preflight report %s from claim %s", file.Path, file.ClaimName))
+		return false, client.IgnoreAlreadyExists(r.Create(ctx, pod))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case pod.Status.Phase == corev1.PodSucceeded:
+		logs, err := r.Pods.Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+		if err != nil {
+			return false, err
+		}
+		report.SetWorkspacePreflight(file, string(logs))
+	case pod.Status.Phase == corev1.PodFailed:
+		report.PreflightNotice = fmt.Sprintf("Could not read the preflight report %s of task %s from claim %s: %s",
+			file.Path, file.Task, file.ClaimName, podFailure(pod))
+	case time.Since(pod.CreationTimestamp.Time) > workspaceReadTimeout:
+		report.PreflightNotice = fmt.Sprintf("Pod %s did not read the preflight report %s of task %s from claim %s within %v",
+			pod.Name, file.Path, file.Task, file.ClaimName, workspaceReadTimeout)
+	default:
+		return false, nil
+	}
+	return true, client.IgnoreNotFound(r.Delete(ctx, pod))
+}
+
+// workspaceFilePod returns the pod printing a file of a workspace of the PipelineRun. It runs with the service
+// account and the image of the TaskRun that wrote the file, which are allowed to mount the claim and pulled.
+func workspaceFilePod(pr *tekton.PipelineRun, file *reconcilers.WorkspaceFile) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: pr.Name + "-preflight-report", Namespace: pr.Namespace},
+		Spec: corev1.PodSpec{
+			RestartPolicy:      corev1.RestartPolicyNever,
+			ServiceAccountName: pr.Spec.TaskRunTemplate.ServiceAccountName,
+			Containers: []corev1.Container{{
+				Name:    "report",
+				Image:   file.Image,
+				Command: []string{"cat", path.Join(workspaceMountPath, file.Path)},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "workspace",
+					MountPath: workspaceMountPath,
+					ReadOnly:  true,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "workspace",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: file.ClaimName,
+					ReadOnly:  true,
+				}},
+			}},
+		},
+	}
+}
+
+// podFailure returns why the container of a failed pod terminated.
+func podFailure(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil {
+			return fmt.Sprintf("%s, exit code %d", terminated.Reason, terminated.ExitCode)
+		}
+	}
+	return pod.Status.Reason
+}
+
+// operatorPipeline returns the OperatorPipeline that installed the Pipeline of the PipelineRun, nil when the
+// Pipeline is not managed by the operator.
+func (r *CertificationReportReconciler) operatorPipeline(ctx context.Context, pr *tekton.PipelineRun) (*v1alpha1.OperatorPipeline, error) {
+	name := pr.Labels[tektonpipeline.PipelineLabelKey]
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	pipeline := &tekton.Pipeline{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: name}, pipeline)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	owner := metav1.GetControllerOf(pipeline)
+	if owner == nil || owner.Kind != "OperatorPipeline" || owner.APIVersion != v1alpha1.GroupVersion.String() {
+		return nil, nil
+	}
+	operatorPipeline := &v1alpha1.OperatorPipeline{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: owner.Name}, operatorPipeline)
+	if errors.IsNotFound(err) || (err == nil && operatorPipeline.UID != owner.UID) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return operatorPipeline, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CertificationReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Only completed PipelineRuns are reported
+	completed := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		pr, ok := obj.(*tekton.PipelineRun)
+		return ok && pr.IsDone()
+	})
+
+	// Only the report ConfigMaps are mapped to their PipelineRun
+	reports := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, ok := obj.GetLabels()[reconcilers.CertificationReportLabel]
+		return ok
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&tekton.PipelineRun{}, builder.WithPredicates(completed)).
+		// A report deleted while its PipelineRun is still there is written again
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(reportPipelineRun), builder.WithPredicates(reports)).
+		Named("certification_report").
+		Complete(r)
+}
+
+// reportPipelineRun maps a report ConfigMap to the PipelineRun it reports.
+func reportPipelineRun(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()[reconcilers.CertificationReportLabel]
+	if len(name) == 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
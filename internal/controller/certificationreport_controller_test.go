@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/reconcilers"
+
+	tektonpipeline "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"knative.dev/pkg/apis"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCertificationReportReconcilerWorkspacePreflight(t *testing.T) {
+	const namespace = "operator-ci"
+
+	operatorPipeline := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: namespace, UID: "uid"},
+	}
+	pipeline := &tekton.Pipeline{ObjectMeta: metav1.ObjectMeta{
+		Name:      "operator-ci-pipeline",
+		Namespace: namespace,
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "OperatorPipeline",
+			Name:       operatorPipeline.Name,
+			UID:        operatorPipeline.UID,
+			Controller: ptr.To(true),
+		}},
+	}}
+	pr := &tekton.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "operator-ci-pipeline-run",
+			Namespace: namespace,
+			Labels:    map[string]string{tektonpipeline.PipelineLabelKey: pipeline.Name},
+		},
+		Spec: tekton.PipelineRunSpec{
+			PipelineRef:     &tekton.PipelineRef{Name: pipeline.Name},
+			TaskRunTemplate: tekton.PipelineTaskRunTemplate{ServiceAccountName: "pipeline"},
+		},
+	}
+	pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: corev1.ConditionFalse})
+	pr.Status.ChildReferences = []tekton.ChildStatusReference{{
+		TypeMeta: runtime.TypeMeta{Kind: "TaskRun"},
+		Name:     "operator-ci-pipeline-run-preflight",
+	}}
+	tr := &tekton.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "operator-ci-pipeline-run-preflight",
+			Namespace: namespace,
+			Labels:    map[string]string{tektonpipeline.PipelineTaskLabelKey: "run-preflight"},
+		},
+		Spec: tekton.TaskRunSpec{Workspaces: []tekton.WorkspaceBinding{{
+			Name:                  "output",
+			SubPath:               "preflight",
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-0123"},
+		}}},
+	}
+	tr.Status.TaskSpec = &tekton.TaskSpec{Steps: []tekton.Step{{Image: "quay.io/example/preflight"}}}
+	tr.Status.Results = []tekton.TaskRunResult{{Name: "result_output_file", Value: *tekton.NewStructuredValues("results.json")}}
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pvc-0123", Namespace: namespace}}
+
+	tests := []struct {
+		name       string
+		claim      bool
+		phase      corev1.PodPhase
+		wantNotice string
+	}{
+		{
+			name:       "pod printed the file",
+			claim:      true,
+			phase:      corev1.PodSucceeded,
+			wantNotice: "File preflight/results.json written by task run-preflight is not a preflight JSON report",
+		},
+		{
+			name:       "pod could not print the file",
+			claim:      true,
+			phase:      corev1.PodFailed,
+			wantNotice: "Could not read the preflight report preflight/results.json of task run-preflight from claim pvc-0123: Error, exit code 1",
+		},
+		{
+			name:       "claim already deleted",
+			wantNotice: "Claim pvc-0123 holding the preflight report of task run-preflight no longer exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := tekton.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			objs := []client.Object{operatorPipeline.DeepCopy(), pipeline.DeepCopy(), pr.DeepCopy(), tr.DeepCopy()}
+			if tt.claim {
+				objs = append(objs, claim.DeepCopy())
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+			r := &CertificationReportReconciler{
+				Client:    c,
+				Scheme:    scheme,
+				APIReader: c,
+				// The fake pods print "fake logs"
+				Pods: kubefake.NewClientset().CoreV1(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(pr)}
+			podKey := types.NamespacedName{Namespace: namespace, Name: pr.Name + "-preflight-report"}
+			reportKey := types.NamespacedName{Namespace: namespace, Name: pr.Name + "-report"}
+
+			if tt.claim {
+				result, err := r.Reconcile(ctx, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.RequeueAfter != workspaceReadRequeue {
+					t.Fatalf("requeue after %v while the pod runs, want %v", result.RequeueAfter, workspaceReadRequeue)
+				}
+				// The report is not written before the pod is done, so the PipelineRun is not pruned
+				if err := c.Get(ctx, reportKey, &corev1.ConfigMap{}); !errors.IsNotFound(err) {
+					t.Fatalf("report written while the pod runs: %v", err)
+				}
+
+				pod := &corev1.Pod{}
+				if err := c.Get(ctx, podKey, pod); err != nil {
+					t.Fatal(err)
+				}
+				if !metav1.IsControlledBy(pod, pr) {
+					t.Fatal("pod is not controlled by the PipelineRun")
+				}
+				container := pod.Spec.Containers[0]
+				if container.Image != "quay.io/example/preflight" || container.Command[1] != "/workspace/preflight/results.json" {
+					t.Fatalf("pod runs %s %v", container.Image, container.Command)
+				}
+				if volume := pod.Spec.Volumes[0].PersistentVolumeClaim; volume == nil || volume.ClaimName != "pvc-0123" || !volume.ReadOnly {
+					t.Fatalf("pod mounts %+v", pod.Spec.Volumes[0])
+				}
+
+				pod.Status.Phase = tt.phase
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+					Name:  "report",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}},
+				}}
+				if err := c.Status().Update(ctx, pod); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Get(ctx, podKey, &corev1.Pod{}); !errors.IsNotFound(err) {
+				t.Fatalf("pod not deleted once done: %v", err)
+			}
+			cm := &corev1.ConfigMap{}
+			if err := c.Get(ctx, reportKey, cm); err != nil {
+				t.Fatal(err)
+			}
+			report := &reconcilers.CertificationReport{}
+			if err := json.Unmarshal([]byte(cm.Data[reconcilers.CertificationReportKey]), report); err != nil {
+				t.Fatal(err)
+			}
+			if report.PreflightNotice != tt.wantNotice || report.PreflightFile != "preflight/results.json" {
+				t.Fatalf("preflight notice %q from %q, want %q", report.PreflightNotice, report.PreflightFile, tt.wantNotice)
+			}
+
+			// The file is not read again for the existing report
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Get(ctx, podKey, &corev1.Pod{}); !errors.IsNotFound(err) {
+				t.Fatalf("pod created again for a report already read: %v", err)
+			}
+		})
+	}
+}
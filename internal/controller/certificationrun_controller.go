@@ -0,0 +1,248 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/reconcilers"
+
+	tektonpipeline "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	pipelineRunCreatedCondition = "PipelineRunCreated"
+	succeededCondition          = "Succeeded"
+	defaultRunPipeline          = "operator-ci-pipeline"
+)
+
+// CertificationRunReconciler reconciles a CertificationRun object
+type CertificationRunReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// APIReader reads from the API server, bypassing the cache. The PipelineRun created by a previous reconcile may
+	// not be in the cache yet, looking it up there would create a second one.
+	APIReader client.Reader
+}
+
+// +kubebuilder:rbac:groups=certification.redhat.com,resources=certificationruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=certification.redhat.com,resources=certificationruns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=certification.redhat.com,resources=certificationruns/finalizers,verbs=update
+// +kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=tekton.dev,resources=taskruns,verbs=get;list;watch
+
+// Reconcile creates the PipelineRun of a CertificationRun and mirrors its status. The PipelineRun is created once,
+// later changes of the OperatorPipeline only apply to new runs.
+func (r *CertificationRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reqLogger := logf.FromContext(ctx, "Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	reqLogger.Info("Reconciling CertificationRun")
+
+	run := &v1alpha1.CertificationRun{}
+	if err := r.Get(ctx, req.NamespacedName, run); err != nil {
+		// Request object not found, could have been deleted after reconcile request
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	origRun := run.DeepCopy()
+
+	var requeue bool
+	var err error
+	if len(run.Status.PipelineRunName) == 0 {
+		requeue, err = r.createPipelineRun(ctx, run)
+	} else {
+		err = r.mirrorPipelineRun(ctx, run)
+	}
+	if err != nil {
+		reqLogger.Error(err, "requeuing with error")
+	}
+
+	if !equality.Semantic.DeepEqual(origRun.Status, run.Status) {
+		if updateErr := r.Status().Update(ctx, run); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+
+	return ctrl.Result{Requeue: requeue}, err
+}
+
+// createPipelineRun creates the PipelineRun of the run, or adopts the one created by a reconcile whose status
+// update failed.
+func (r *CertificationRunReconciler) createPipelineRun(ctx context.Context, run *v1alpha1.CertificationRun) (bool, error) {
+	run.Status.Phase = v1alpha1.CertificationRunPending
+
+	existing := &tekton.PipelineRunList{}
+	if err := r.APIReader.List(ctx, existing, client.InNamespace(run.Namespace), client.MatchingLabels{reconcilers.CertificationRunLabel: run.Name}); err != nil {
+		return true, err
+	}
+	for _, pr := range existing.Items {
+		if metav1.IsControlledBy(&pr, run) {
+			run.Status.PipelineRunName = pr.Name
+			setRunCondition(run, pipelineRunCreatedCondition, metav1.ConditionTrue, "Created", fmt.Sprintf("Created PipelineRun %s", pr.Name))
+			return false, nil
+		}
+	}
+
+	operatorPipeline := &v1alpha1.OperatorPipeline{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: run.Namespace, Name: run.Spec.OperatorPipelineName}, operatorPipeline)
+	if errors.IsNotFound(err) {
+		setRunCondition(run, pipelineRunCreatedCondition, metav1.ConditionFalse, "OperatorPipelineNotFound",
+			fmt.Sprintf("OperatorPipeline %s does not exist", run.Spec.OperatorPipelineName))
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+
+	pipelineName := run.Spec.Pipeline
+	if len(pipelineName) == 0 {
+		pipelineName = defaultRunPipeline
+	}
+	pipeline := &tekton.Pipeline{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: run.Namespace, Name: pipelineName}, pipeline)
+	if errors.IsNotFound(err) {
+		setRunCondition(run, pipelineRunCreatedCondition, metav1.ConditionFalse, "PipelineNotFound",
+			fmt.Sprintf("Pipeline %s is not installed, select it in OperatorPipeline %s", pipelineName, operatorPipeline.Name))
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+
+	pr := reconcilers.NewPipelineRun(operatorPipeline, pipeline, run)
+	if err := controllerutil.SetControllerReference(run, pr, r.Scheme); err != nil {
+		return true, err
+	}
+	if err := r.Create(ctx, pr); err != nil {
+		setRunCondition(run, pipelineRunCreatedCondition, metav1.ConditionFalse, "Failed", err.Error())
+		return true, err
+	}
+
+	run.Status.PipelineRunName = pr.Name
+	setRunCondition(run, pipelineRunCreatedCondition, metav1.ConditionTrue, "Created", fmt.Sprintf("Created PipelineRun %s", pr.Name))
+	return false, nil
+}
+
+// mirrorPipelineRun copies the phase, TaskRun statuses and results of the PipelineRun to the status of the run.
+// Once the PipelineRun completed, its status is kept when the PipelineRun is deleted.
+func (r *CertificationRunReconciler) mirrorPipelineRun(ctx context.Context, run *v1alpha1.CertificationRun) error {
+	pr := &tekton.PipelineRun{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: run.Namespace, Name: run.Status.PipelineRunName}, pr)
+	if errors.IsNotFound(err) {
+		if run.Status.Phase != v1alpha1.CertificationRunSucceeded && run.Status.Phase != v1alpha1.CertificationRunFailed {
+			run.Status.Phase = v1alpha1.CertificationRunFailed
+			setRunCondition(run, succeededCondition, metav1.ConditionFalse, "PipelineRunDeleted",
+				fmt.Sprintf("PipelineRun %s was deleted before it completed", run.Status.PipelineRunName))
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	condition := pr.Status.GetCondition(apis.ConditionSucceeded)
+	run.Status.Phase = runPhase(condition)
+	run.Status.StartTime = pr.Status.StartTime
+	run.Status.CompletionTime = pr.Status.CompletionTime
+	if condition != nil {
+		reason := condition.Reason
+		if len(reason) == 0 {
+			reason = string(condition.Status)
+		}
+		setRunCondition(run, succeededCondition, metav1.ConditionStatus(condition.Status), reason, condition.Message)
+	}
+
+	var results []v1alpha1.CertificationRunResult
+	for _, result := range pr.Status.Results {
+		value, err := reconcilers.ResultValue(result.Value)
+		if err != nil {
+			return err
+		}
+		results = append(results, v1alpha1.CertificationRunResult{Name: result.Name, Value: value})
+	}
+	run.Status.Results = results
+
+	taskRuns, err := reconcilers.PipelineRunTaskRuns(ctx, r.Client, pr)
+	if err != nil {
+		return err
+	}
+	var tasks []v1alpha1.CertificationTaskStatus
+	for _, tr := range taskRuns {
+		task := v1alpha1.CertificationTaskStatus{PipelineTaskName: tr.Labels[tektonpipeline.PipelineTaskLabelKey], TaskRunName: tr.Name}
+		trCondition := tr.Status.GetCondition(apis.ConditionSucceeded)
+		task.Phase = runPhase(trCondition)
+		if trCondition != nil {
+			task.Reason = trCondition.Reason
+			task.Message = trCondition.Message
+		}
+		tasks = append(tasks, task)
+	}
+	run.Status.Tasks = tasks
+
+	return nil
+}
+
+// runPhase returns the phase of a PipelineRun or TaskRun from its Succeeded condition.
+func runPhase(condition *apis.Condition) v1alpha1.CertificationRunPhase {
+	switch {
+	case condition == nil:
+		return v1alpha1.CertificationRunPending
+	case condition.IsTrue():
+		return v1alpha1.CertificationRunSucceeded
+	case condition.IsFalse():
+		return v1alpha1.CertificationRunFailed
+	case condition.Reason == tekton.PipelineRunReasonPending.String():
+		return v1alpha1.CertificationRunPending
+	default:
+		return v1alpha1.CertificationRunRunning
+	}
+}
+
+func setRunCondition(run *v1alpha1.CertificationRun, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&run.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: run.Generation,
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CertificationRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.CertificationRun{}).
+		Owns(&tekton.PipelineRun{}).
+		Named("certification_run").
+		Complete(r)
+}
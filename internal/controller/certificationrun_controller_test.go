@@ -0,0 +1,430 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/reconcilers"
+
+	tektonpipeline "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"knative.dev/pkg/apis"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCertificationRunReconcilerCreatePipelineRun(t *testing.T) {
+	const namespace = "operator-ci"
+
+	operatorPipeline := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: namespace},
+		Spec: v1alpha1.OperatorPipelineSpec{
+			KubeconfigSecretName:     "cluster-kubeconfig",
+			DockerRegistrySecretName: "registry-dockerconfig-secret",
+			ServiceAccountName:       "certification",
+		},
+	}
+	pipeline := &tekton.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-ci-pipeline", Namespace: namespace},
+		Spec: tekton.PipelineSpec{
+			Params: tekton.ParamSpecs{
+				{Name: "git_repo_url"},
+				{Name: "git_revision"},
+				{Name: "bundle_path"},
+				{Name: "kubeconfig_secret_name"},
+				{Name: "env"},
+			},
+			Workspaces: []tekton.PipelineWorkspaceDeclaration{
+				{Name: "pipeline"},
+				{Name: "kubeconfig"},
+				{Name: "ssh-dir", Optional: true},
+				{Name: "registry-credentials", Optional: true},
+			},
+		},
+	}
+	run := &v1alpha1.CertificationRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "certify-etcd", Namespace: namespace, UID: "run-uid"},
+		Spec: v1alpha1.CertificationRunSpec{
+			OperatorPipelineName: operatorPipeline.Name,
+			GitRepoURL:           "https://github.com/example/operators.git",
+			GitRevision:          "main",
+			BundlePath:           "operators/etcd/0.0.1",
+			OCPVersion:           "4.16",
+			// Params the Pipeline does not declare are left out
+			Params: map[string]string{"env": "stage", "unknown": "value"},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		objs             []client.Object
+		wantReason       string
+		wantRequeue      bool
+		wantPipelineRuns int
+	}{
+		{
+			name:             "PipelineRun created",
+			objs:             []client.Object{operatorPipeline, pipeline},
+			wantReason:       "Created",
+			wantPipelineRuns: 1,
+		},
+		{
+			name:        "OperatorPipeline not found",
+			objs:        []client.Object{pipeline},
+			wantReason:  "OperatorPipelineNotFound",
+			wantRequeue: true,
+		},
+		{
+			name:        "Pipeline not installed",
+			objs:        []client.Object{operatorPipeline},
+			wantReason:  "PipelineNotFound",
+			wantRequeue: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := tekton.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			objs := []client.Object{run.DeepCopy()}
+			for _, obj := range tt.objs {
+				objs = append(objs, obj.DeepCopyObject().(client.Object))
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&v1alpha1.CertificationRun{}).Build()
+			r := &CertificationRunReconciler{Client: c, Scheme: scheme, APIReader: c}
+
+			result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(run)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Requeue != tt.wantRequeue {
+				t.Fatalf("requeue %v, want %v", result.Requeue, tt.wantRequeue)
+			}
+
+			got := &v1alpha1.CertificationRun{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(run), got); err != nil {
+				t.Fatal(err)
+			}
+			condition := meta.FindStatusCondition(got.Status.Conditions, pipelineRunCreatedCondition)
+			if condition == nil || condition.Reason != tt.wantReason {
+				t.Fatalf("condition %+v, want reason %s", condition, tt.wantReason)
+			}
+			if got.Status.Phase != v1alpha1.CertificationRunPending {
+				t.Fatalf("phase %s, want %s", got.Status.Phase, v1alpha1.CertificationRunPending)
+			}
+
+			prs := &tekton.PipelineRunList{}
+			if err := c.List(ctx, prs); err != nil {
+				t.Fatal(err)
+			}
+			if len(prs.Items) != tt.wantPipelineRuns {
+				t.Fatalf("%d PipelineRuns, want %d", len(prs.Items), tt.wantPipelineRuns)
+			}
+			if tt.wantPipelineRuns == 0 {
+				return
+			}
+
+			pr := prs.Items[0]
+			if got.Status.PipelineRunName != pr.Name {
+				t.Fatalf("status names PipelineRun %q, want %q", got.Status.PipelineRunName, pr.Name)
+			}
+			if !metav1.IsControlledBy(&pr, got) {
+				t.Fatal("PipelineRun is not controlled by the CertificationRun")
+			}
+			wantLabels := map[string]string{reconcilers.CertificationRunLabel: run.Name, reconcilers.OCPVersionLabel: "4.16"}
+			if !reflect.DeepEqual(pr.Labels, wantLabels) {
+				t.Fatalf("labels %v, want %v", pr.Labels, wantLabels)
+			}
+			if pr.Spec.PipelineRef == nil || pr.Spec.PipelineRef.Name != pipeline.Name {
+				t.Fatalf("PipelineRun runs %+v, want %s", pr.Spec.PipelineRef, pipeline.Name)
+			}
+			if pr.Spec.TaskRunTemplate.ServiceAccountName != "certification" {
+				t.Fatalf("service account %q, want certification", pr.Spec.TaskRunTemplate.ServiceAccountName)
+			}
+
+			params := map[string]string{}
+			for _, param := range pr.Spec.Params {
+				params[param.Name] = param.Value.StringVal
+			}
+			wantParams := map[string]string{
+				"git_repo_url":           "https://github.com/example/operators.git",
+				"git_revision":           "main",
+				"bundle_path":            "operators/etcd/0.0.1",
+				"kubeconfig_secret_name": "cluster-kubeconfig",
+				"env":                    "stage",
+			}
+			if !reflect.DeepEqual(params, wantParams) {
+				t.Fatalf("params %v, want %v", params, wantParams)
+			}
+
+			workspaces := map[string]tekton.WorkspaceBinding{}
+			for _, workspace := range pr.Spec.Workspaces {
+				workspaces[workspace.Name] = workspace
+			}
+			if len(workspaces) != 3 {
+				t.Fatalf("workspaces %+v, want pipeline, kubeconfig and registry-credentials", pr.Spec.Workspaces)
+			}
+			if workspaces["pipeline"].VolumeClaimTemplate == nil {
+				t.Fatalf("pipeline workspace %+v, want a volume claim template", workspaces["pipeline"])
+			}
+			for name, secretName := range map[string]string{"kubeconfig": "cluster-kubeconfig", "registry-credentials": "registry-dockerconfig-secret"} {
+				if secret := workspaces[name].Secret; secret == nil || secret.SecretName != secretName {
+					t.Fatalf("%s workspace %+v, want secret %s", name, workspaces[name], secretName)
+				}
+			}
+		})
+	}
+}
+
+func TestCertificationRunReconcilerAdoptPipelineRun(t *testing.T) {
+	const namespace = "operator-ci"
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := tekton.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	run := &v1alpha1.CertificationRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "certify-etcd", Namespace: namespace, UID: "run-uid"},
+		Spec: v1alpha1.CertificationRunSpec{
+			OperatorPipelineName: "operator-pipeline",
+			GitRepoURL:           "https://github.com/example/operators.git",
+			BundlePath:           "operators/etcd/0.0.1",
+		},
+	}
+	controllerRef := metav1.OwnerReference{
+		APIVersion: v1alpha1.GroupVersion.String(),
+		Kind:       "CertificationRun",
+		Name:       run.Name,
+		UID:        run.UID,
+		Controller: ptr.To(true),
+	}
+	// Created by a reconcile whose status update failed
+	created := &tekton.PipelineRun{ObjectMeta: metav1.ObjectMeta{
+		Name:            "certify-etcd-x7k2p",
+		Namespace:       namespace,
+		Labels:          map[string]string{reconcilers.CertificationRunLabel: run.Name},
+		OwnerReferences: []metav1.OwnerReference{controllerRef},
+	}}
+	// Labelled by hand, but not controlled by the run
+	unowned := &tekton.PipelineRun{ObjectMeta: metav1.ObjectMeta{
+		Name:      "certify-etcd-manual",
+		Namespace: namespace,
+		Labels:    map[string]string{reconcilers.CertificationRunLabel: run.Name},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(run, unowned, created).WithStatusSubresource(run).Build()
+	r := &CertificationRunReconciler{Client: c, Scheme: scheme, APIReader: c}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(run)}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &v1alpha1.CertificationRun{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(run), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.PipelineRunName != created.Name {
+		t.Fatalf("status names PipelineRun %q, want %q", got.Status.PipelineRunName, created.Name)
+	}
+	if condition := meta.FindStatusCondition(got.Status.Conditions, pipelineRunCreatedCondition); condition == nil || condition.Reason != "Created" {
+		t.Fatalf("condition %+v, want reason Created", condition)
+	}
+	prs := &tekton.PipelineRunList{}
+	if err := c.List(ctx, prs); err != nil {
+		t.Fatal(err)
+	}
+	if len(prs.Items) != 2 {
+		t.Fatalf("%d PipelineRuns, want no new one", len(prs.Items))
+	}
+}
+
+func TestCertificationRunReconcilerMirrorPipelineRun(t *testing.T) {
+	const namespace = "operator-ci"
+
+	startTime := metav1.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	completionTime := metav1.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	taskRun := func(name, task string, status corev1.ConditionStatus, reason string) *tekton.TaskRun {
+		tr := &tekton.TaskRun{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{tektonpipeline.PipelineTaskLabelKey: task},
+		}}
+		tr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: status, Reason: reason, Message: reason + " message"})
+		return tr
+	}
+	pipelineRun := func(status corev1.ConditionStatus, reason string) *tekton.PipelineRun {
+		pr := &tekton.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "certify-etcd-x7k2p", Namespace: namespace}}
+		pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: status, Reason: reason, Message: reason + " message"})
+		pr.Status.StartTime = &startTime
+		if status != corev1.ConditionUnknown {
+			pr.Status.CompletionTime = &completionTime
+		}
+		pr.Status.Results = []tekton.PipelineRunResult{
+			{Name: "bundle_digest", Value: *tekton.NewStructuredValues("sha256:0123")},
+			{Name: "ocp_versions", Value: *tekton.NewStructuredValues("4.15", "4.16")},
+		}
+		// The TaskRun of the deleted task is left out
+		for _, name := range []string{"certify-etcd-x7k2p-checkout", "certify-etcd-x7k2p-preflight", "certify-etcd-x7k2p-deleted"} {
+			pr.Status.ChildReferences = append(pr.Status.ChildReferences, tekton.ChildStatusReference{
+				TypeMeta: runtime.TypeMeta{Kind: "TaskRun"},
+				Name:     name,
+			})
+		}
+		return pr
+	}
+	taskRuns := []client.Object{
+		taskRun("certify-etcd-x7k2p-checkout", "checkout", corev1.ConditionTrue, "Succeeded"),
+		taskRun("certify-etcd-x7k2p-preflight", "run-preflight", corev1.ConditionUnknown, "Running"),
+	}
+	results := []v1alpha1.CertificationRunResult{
+		{Name: "bundle_digest", Value: "sha256:0123"},
+		{Name: "ocp_versions", Value: `["4.15","4.16"]`},
+	}
+	tasks := []v1alpha1.CertificationTaskStatus{
+		{PipelineTaskName: "checkout", TaskRunName: "certify-etcd-x7k2p-checkout", Phase: v1alpha1.CertificationRunSucceeded, Reason: "Succeeded", Message: "Succeeded message"},
+		{PipelineTaskName: "run-preflight", TaskRunName: "certify-etcd-x7k2p-preflight", Phase: v1alpha1.CertificationRunRunning, Reason: "Running", Message: "Running message"},
+	}
+
+	tests := []struct {
+		name           string
+		phase          v1alpha1.CertificationRunPhase
+		pipelineRun    *tekton.PipelineRun
+		wantPhase      v1alpha1.CertificationRunPhase
+		wantReason     string
+		wantCompletion bool
+		wantResults    []v1alpha1.CertificationRunResult
+		wantTasks      []v1alpha1.CertificationTaskStatus
+	}{
+		{
+			name:        "running",
+			phase:       v1alpha1.CertificationRunPending,
+			pipelineRun: pipelineRun(corev1.ConditionUnknown, "Running"),
+			wantPhase:   v1alpha1.CertificationRunRunning,
+			wantReason:  "Running",
+			wantResults: results,
+			wantTasks:   tasks,
+		},
+		{
+			name:           "succeeded",
+			phase:          v1alpha1.CertificationRunRunning,
+			pipelineRun:    pipelineRun(corev1.ConditionTrue, "Succeeded"),
+			wantPhase:      v1alpha1.CertificationRunSucceeded,
+			wantReason:     "Succeeded",
+			wantCompletion: true,
+			wantResults:    results,
+			wantTasks:      tasks,
+		},
+		{
+			name:           "failed",
+			phase:          v1alpha1.CertificationRunRunning,
+			pipelineRun:    pipelineRun(corev1.ConditionFalse, "Failed"),
+			wantPhase:      v1alpha1.CertificationRunFailed,
+			wantReason:     "Failed",
+			wantCompletion: true,
+			wantResults:    results,
+			wantTasks:      tasks,
+		},
+		{
+			name:       "deleted before it completed",
+			phase:      v1alpha1.CertificationRunRunning,
+			wantPhase:  v1alpha1.CertificationRunFailed,
+			wantReason: "PipelineRunDeleted",
+		},
+		{
+			name:      "deleted once completed",
+			phase:     v1alpha1.CertificationRunSucceeded,
+			wantPhase: v1alpha1.CertificationRunSucceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := tekton.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			run := &v1alpha1.CertificationRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "certify-etcd", Namespace: namespace, UID: "run-uid"},
+				Spec: v1alpha1.CertificationRunSpec{
+					OperatorPipelineName: "operator-pipeline",
+					GitRepoURL:           "https://github.com/example/operators.git",
+					BundlePath:           "operators/etcd/0.0.1",
+				},
+				Status: v1alpha1.CertificationRunStatus{Phase: tt.phase, PipelineRunName: "certify-etcd-x7k2p"},
+			}
+			objs := []client.Object{run}
+			for _, obj := range taskRuns {
+				objs = append(objs, obj.DeepCopyObject().(client.Object))
+			}
+			if tt.pipelineRun != nil {
+				objs = append(objs, tt.pipelineRun)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(run).Build()
+			r := &CertificationRunReconciler{Client: c, Scheme: scheme, APIReader: c}
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(run)}); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &v1alpha1.CertificationRun{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(run), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.wantPhase {
+				t.Fatalf("phase %s, want %s", got.Status.Phase, tt.wantPhase)
+			}
+			condition := meta.FindStatusCondition(got.Status.Conditions, succeededCondition)
+			switch {
+			case len(tt.wantReason) == 0 && condition != nil:
+				t.Fatalf("condition %+v, want none", condition)
+			case len(tt.wantReason) > 0 && (condition == nil || condition.Reason != tt.wantReason):
+				t.Fatalf("condition %+v, want reason %s", condition, tt.wantReason)
+			}
+			if !reflect.DeepEqual(got.Status.Results, tt.wantResults) {
+				t.Fatalf("results %+v, want %+v", got.Status.Results, tt.wantResults)
+			}
+			if !reflect.DeepEqual(got.Status.Tasks, tt.wantTasks) {
+				t.Fatalf("tasks %+v, want %+v", got.Status.Tasks, tt.wantTasks)
+			}
+			if tt.pipelineRun == nil {
+				return
+			}
+			if got.Status.StartTime == nil || !got.Status.StartTime.Equal(&startTime) {
+				t.Fatalf("start time %v, want %v", got.Status.StartTime, startTime)
+			}
+			if (got.Status.CompletionTime != nil) != tt.wantCompletion {
+				t.Fatalf("completion time %v, want one %v", got.Status.CompletionTime, tt.wantCompletion)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/reconcilers"
@@ -29,11 +30,17 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const operatorPipelineFinalizer = "certification.redhat.com/finalizer"
@@ -44,17 +51,26 @@ var log = logf.Log.WithName("controller_operatorpipeline")
 type OperatorPipelineReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// RepositoryCache is shared by all reconciles so the operator-pipelines repository is not fetched on every one
+	RepositoryCache *reconcilers.RepositoryCache
 }
 
 // +kubebuilder:rbac:groups=certification.redhat.com,resources=operatorpipelines,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=certification.redhat.com,resources=operatorpipelines/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=certification.redhat.com,resources=operatorpipelines/finalizers,verbs=update
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=image.openshift.io,resources=imagestreams,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=image.openshift.io,resources=imagestreamimports,verbs=create
 // +kubebuilder:rbac:groups=security.openshift.io,resources=securitycontextconstraints,verbs=*
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,resourceNames=tekton-triggers-eventlistener-roles;tekton-triggers-eventlistener-clusterroles,verbs=bind
 // +kubebuilder:rbac:groups=tekton.dev,resources=pipelines;tasks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=triggers.tekton.dev,resources=eventlisteners;triggerbindings;triggertemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -78,33 +94,9 @@ func (r *OperatorPipelineReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	isOperatorPipelineMarkedToBeDeleted := currentPipeline.GetDeletionTimestamp() != nil
 	if isOperatorPipelineMarkedToBeDeleted {
 		if controllerutil.ContainsFinalizer(currentPipeline, operatorPipelineFinalizer) {
-			namespacePipelines := &v1alpha1.OperatorPipelineList{}
-
-			// creating listOptions inorder to know the number of OperatorPipeline resources in the given namespace
-			// if last CR in namespace we can remove the ClusterRoleBinding associated with the namespace
-			listOptions := client.InNamespace(currentPipeline.Namespace)
-			if err := r.List(ctx, namespacePipelines, listOptions); err != nil {
-				return ctrl.Result{}, err
-			}
-
-			// if the length is 1 we know this is the last CR in a given namespace and can remove the ClusterRoleBinding
-			// associated with this namespace
-			if len(namespacePipelines.Items) == 1 {
-				if err := r.deleteClusterRoleBinding(ctx, log, currentPipeline.Namespace); err != nil {
-					return ctrl.Result{}, err
-				}
-			}
-
-			clusterPipelines := &v1alpha1.OperatorPipelineList{}
-			// not creating listOptions since we want to know the total number of OperatorPipelines in the entire cluster
-			// if last CR in cluster we can remove the SCC and ClusterRole
-			if err := r.List(ctx, clusterPipelines); err != nil {
-				return ctrl.Result{}, err
-			}
-
-			// if the length is 1 we know this is the last CR in the entire cluster and can remove the SCC and ClusterRole
-			if len(clusterPipelines.Items) == 1 {
-				if err := r.deleteSCCandClusterRole(ctx, log); err != nil {
+			// A ReportOnly pipeline leaves the cluster-scoped objects to the pipelines that enforce them
+			if currentPipeline.Spec.ReconcileMode != v1alpha1.ReconcileModeReportOnly {
+				if err := r.deleteClusterResources(ctx, currentPipeline); err != nil {
 					return ctrl.Result{}, err
 				}
 			}
@@ -120,17 +112,25 @@ func (r *OperatorPipelineReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
+	// The dependencies and status reconcilers read the same manifests, they are only resolved once
+	manifests := reconcilers.NewResolvedManifests(r.Client)
 	resourceReconcilers := []reconcilers.Reconciler{
-		reconcilers.NewPipelineGitRepoReconciler(r.Client, reqLogger, r.Scheme),
-		reconcilers.NewPipeDependenciesReconciler(r.Client, reqLogger, r.Scheme),
+		reconcilers.NewPipelineGitRepoReconciler(r.Client, reqLogger, r.Scheme, r.RepositoryCache),
+		reconcilers.NewPipeDependenciesReconciler(r.Client, reqLogger, r.Scheme, manifests),
+		reconcilers.NewServiceAccountReconciler(r.Client, reqLogger, r.Scheme),
+		reconcilers.NewWorkspacesReconciler(r.Client, reqLogger, r.Scheme),
+		reconcilers.NewTriggersReconciler(r.Client, reqLogger, r.Scheme),
+		reconcilers.NewRetentionReconciler(r.Client, reqLogger, r.Scheme),
 		reconcilers.NewCertifiedImageStreamReconciler(r.Client, reqLogger, r.Scheme),
 		reconcilers.NewMarketplaceImageStreamReconciler(r.Client, reqLogger, r.Scheme),
-		reconcilers.NewStatusReconciler(r.Client, reqLogger, r.Scheme),
+		reconcilers.NewStatusReconciler(r.Client, reqLogger, r.Scheme, manifests),
 	}
 
 	requeueResult := false
 	var errResult error = nil
 	pipeline := currentPipeline.DeepCopy()
+	// The deprecated pipeline booleans are turned into pipeline selections
+	pipeline.Default()
 	for _, r := range resourceReconcilers {
 		requeue, err := r.Reconcile(ctx, pipeline)
 		if err != nil && errResult == nil {
@@ -141,6 +141,16 @@ func (r *OperatorPipelineReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		requeueResult = requeueResult || requeue
 	}
 
+	// Reconcilers waiting for time to pass ask for a later reconcile, the earliest one wins
+	var requeueAfter time.Duration
+	for _, r := range resourceReconcilers {
+		if delayed, ok := r.(reconcilers.DelayedRequeuer); ok {
+			if after := delayed.RequeueAfter(); after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+				requeueAfter = after
+			}
+		}
+	}
+
 	// Adding finalizer to OperatorPipelines CR
 	if !controllerutil.ContainsFinalizer(currentPipeline, operatorPipelineFinalizer) {
 		controllerutil.AddFinalizer(currentPipeline, operatorPipelineFinalizer)
@@ -150,7 +160,60 @@ func (r *OperatorPipelineReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	// Just return the first error reported. It's the most likely issue that needs to be solved.
-	return ctrl.Result{Requeue: requeueResult}, errResult
+	// An immediate requeue computes the delays again, so it takes precedence
+	if requeueResult {
+		requeueAfter = 0
+	}
+	return ctrl.Result{Requeue: requeueResult, RequeueAfter: requeueAfter}, errResult
+}
+
+// deleteClusterResources deletes the ClusterRoleBinding of the namespace when the pipeline is the last one of its
+// namespace, and the SCC and ClusterRole when it is the last one of the cluster.
+func (r *OperatorPipelineReconciler) deleteClusterResources(ctx context.Context, currentPipeline *v1alpha1.OperatorPipeline) error {
+	namespacePipelines := &v1alpha1.OperatorPipelineList{}
+
+	// creating listOptions inorder to know the number of OperatorPipeline resources in the given namespace
+	// if last CR in namespace we can remove the ClusterRoleBinding associated with the namespace
+	listOptions := client.InNamespace(currentPipeline.Namespace)
+	if err := r.List(ctx, namespacePipelines, listOptions); err != nil {
+		return err
+	}
+
+	// if this is the last enforcing CR in a given namespace we can remove the ClusterRoleBinding associated with
+	// this namespace
+	if enforcingPipelines(namespacePipelines.Items) == 1 {
+		if err := r.deleteClusterRoleBinding(ctx, log, currentPipeline.Namespace); err != nil {
+			return err
+		}
+	}
+
+	clusterPipelines := &v1alpha1.OperatorPipelineList{}
+	// not creating listOptions since we want to know the total number of OperatorPipelines in the entire cluster
+	// if last CR in cluster we can remove the SCC and ClusterRole
+	if err := r.List(ctx, clusterPipelines); err != nil {
+		return err
+	}
+
+	// if this is the last enforcing CR in the entire cluster we can remove the SCC and ClusterRole
+	if enforcingPipelines(clusterPipelines.Items) == 1 {
+		if err := r.deleteSCCandClusterRole(ctx, log); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enforcingPipelines counts the pipelines that are not in ReportOnly mode, ReportOnly pipelines do not use the
+// cluster-scoped objects.
+func enforcingPipelines(pipelines []v1alpha1.OperatorPipeline) int {
+	count := 0
+	for _, pipeline := range pipelines {
+		if pipeline.Spec.ReconcileMode != v1alpha1.ReconcileModeReportOnly {
+			count++
+		}
+	}
+	return count
 }
 
 func (r *OperatorPipelineReconciler) deleteSCCandClusterRole(ctx context.Context, log logr.Logger) error {
@@ -209,15 +272,46 @@ func (r *OperatorPipelineReconciler) deleteClusterRoleBinding(ctx context.Contex
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *OperatorPipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.RepositoryCache == nil {
+		r.RepositoryCache = reconcilers.NewRepositoryCache(reconcilers.DefaultFetchInterval)
+	}
+
+	completed := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		pr, ok := obj.(*tekton.PipelineRun)
+		return ok && pr.IsDone()
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.OperatorPipeline{}).
 		Owns(&corev1.Secret{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&corev1.ConfigMap{}).
 		Owns(&imagev1.ImageStream{}).
 		Owns(&tekton.Pipeline{}).
 		Owns(&tekton.Task{}).
 		Owns(&securityv1.SecurityContextConstraints{}).
 		Owns(&rbacv1.ClusterRole{}).
 		Owns(&rbacv1.ClusterRoleBinding{}).
+		// Completed PipelineRuns are pruned by the retention policy of the OperatorPipeline that installed their Pipeline
+		Watches(&tekton.PipelineRun{}, handler.EnqueueRequestsFromMapFunc(r.pipelineRunOperatorPipeline), builder.WithPredicates(completed)).
 		Named("operator_pipeline").
 		Complete(r)
 }
+
+// pipelineRunOperatorPipeline maps a PipelineRun to the OperatorPipeline that installed its Pipeline, if any.
+func (r *OperatorPipelineReconciler) pipelineRunOperatorPipeline(ctx context.Context, obj client.Object) []reconcile.Request {
+	pr, ok := obj.(*tekton.PipelineRun)
+	if !ok || pr.Spec.PipelineRef == nil {
+		return nil
+	}
+
+	pipeline := &tekton.Pipeline{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Spec.PipelineRef.Name}, pipeline); err != nil {
+		return nil
+	}
+	owner := metav1.GetControllerOf(pipeline)
+	if owner == nil || owner.Kind != "OperatorPipeline" || owner.APIVersion != v1alpha1.GroupVersion.String() {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: owner.Name}}}
+}
@@ -3,7 +3,11 @@ package errors
 import "errors"
 
 var (
-	ErrSecretNotFound          = errors.New("could not find existing secret")
-	ErrInvalidSecret           = errors.New("the secret does not contain a valid key")
-	ErrGitRepoPathNotSpecified = errors.New("the GIT_REPO_PATH environment variable was not specified")
+	ErrSecretNotFound             = errors.New("could not find existing secret")
+	ErrInvalidSecret              = errors.New("the secret does not contain a valid key")
+	ErrGitRepoPathNotSpecified    = errors.New("the GIT_REPO_PATH environment variable was not specified")
+	ErrSignatureInvalid           = errors.New("the signature could not be verified with the trusted keys")
+	ErrPipelinesRepoNotCheckedOut = errors.New("the operator-pipelines repository has not been checked out")
+	ErrManifestKindNotAllowed     = errors.New("the manifest kind is not allowed")
+	ErrManifestsInvalid           = errors.New("the pipeline manifests are not valid")
 )
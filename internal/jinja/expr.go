@@ -0,0 +1,483 @@
+package jinja
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// undefined is the value of a variable that is not set, it carries the name for the error message.
+type undefined struct {
+	name string
+}
+
+type expr interface {
+	eval(s *scope) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (e *literal) eval(_ *scope) (interface{}, error) {
+	return e.value, nil
+}
+
+type variable struct {
+	path []string
+}
+
+func (e *variable) eval(s *scope) (interface{}, error) {
+	name := strings.Join(e.path, ".")
+	value, ok, err := s.lookup(e.path[0])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return undefined{name: name}, nil
+	}
+
+	for _, key := range e.path[1:] {
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			return undefined{name: name}, nil
+		}
+		if value, ok = m[key]; !ok {
+			return undefined{name: name}, nil
+		}
+	}
+	return value, nil
+}
+
+type notExpr struct {
+	operand expr
+}
+
+func (e *notExpr) eval(s *scope) (interface{}, error) {
+	value, err := e.operand.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e *binaryExpr) eval(s *scope) (interface{}, error) {
+	left, err := e.left.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// and / or short circuit and return the deciding operand, like Jinja
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return e.right.eval(s)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return e.right.eval(s)
+	}
+
+	right, err := e.right.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, operand := range []interface{}{left, right} {
+		if u, ok := operand.(undefined); ok {
+			return nil, fmt.Errorf("%s is undefined", u.name)
+		}
+	}
+
+	equal := toString(left) == toString(right)
+	if e.op == "!=" {
+		return !equal, nil
+	}
+	return equal, nil
+}
+
+type definedExpr struct {
+	operand expr
+	negate  bool
+}
+
+func (e *definedExpr) eval(s *scope) (interface{}, error) {
+	value, err := e.operand.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	_, isUndefined := value.(undefined)
+	return isUndefined == e.negate, nil
+}
+
+type filterExpr struct {
+	operand expr
+	name    string
+	args    []expr
+}
+
+func (e *filterExpr) eval(s *scope) (interface{}, error) {
+	value, err := e.operand.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, len(e.args))
+	for _, arg := range e.args {
+		v, err := arg.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	filter, ok := filters[e.name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter %s", e.name)
+	}
+
+	// Only the default filter accepts undefined values
+	if u, isUndefined := value.(undefined); isUndefined && e.name != "default" && e.name != "d" {
+		return nil, fmt.Errorf("%s is undefined", u.name)
+	}
+
+	return filter(value, args)
+}
+
+// filters are the supported Jinja and Ansible filters.
+var filters = map[string]func(value interface{}, args []interface{}) (interface{}, error){
+	"default": defaultFilter,
+	"d":       defaultFilter,
+	"lower": func(value interface{}, _ []interface{}) (interface{}, error) {
+		return strings.ToLower(toString(value)), nil
+	},
+	"upper": func(value interface{}, _ []interface{}) (interface{}, error) {
+		return strings.ToUpper(toString(value)), nil
+	},
+	"trim": func(value interface{}, _ []interface{}) (interface{}, error) {
+		return strings.TrimSpace(toString(value)), nil
+	},
+	"string": func(value interface{}, _ []interface{}) (interface{}, error) {
+		return toString(value), nil
+	},
+	"int": func(value interface{}, _ []interface{}) (interface{}, error) {
+		i, err := strconv.ParseFloat(toString(value), 64)
+		if err != nil {
+			return 0, nil
+		}
+		return int64(i), nil
+	},
+	"bool": func(value interface{}, _ []interface{}) (interface{}, error) {
+		switch strings.ToLower(toString(value)) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		default:
+			return false, nil
+		}
+	},
+	"replace": func(value interface{}, args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("replace expects 2 arguments, got %d", len(args))
+		}
+		return strings.ReplaceAll(toString(value), toString(args[0]), toString(args[1])), nil
+	},
+	"to_json": func(value interface{}, _ []interface{}) (interface{}, error) {
+		b, err := json.Marshal(value)
+		return string(b), err
+	},
+}
+
+// defaultFilter replaces undefined values, and falsy ones too when the second argument is true.
+func defaultFilter(value interface{}, args []interface{}) (interface{}, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("default expects 1 or 2 arguments, got %d", len(args))
+	}
+	if _, isUndefined := value.(undefined); isUndefined {
+		return args[0], nil
+	}
+	if len(args) == 2 && truthy(args[1]) && !truthy(value) {
+		return args[0], nil
+	}
+	return value, nil
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return v
+	case string:
+		return len(v) > 0
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "None"
+	case string:
+		return v
+	case bool:
+		// Python spells booleans with a capital
+		if v {
+			return "True"
+		}
+		return "False"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type token struct {
+	kind  string // ident, string, number, op or eof
+	value string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexRune(input[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in %q", input)
+			}
+			tokens = append(tokens, token{kind: "string", value: input[i+1 : i+1+end]})
+			i += end + 2
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(input) && (unicode.IsDigit(rune(input[j])) || input[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: "number", value: input[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(input) && (unicode.IsLetter(rune(input[j])) || unicode.IsDigit(rune(input[j])) || input[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{kind: "ident", value: input[i:j]})
+			i = j
+		case strings.HasPrefix(input[i:], "==") || strings.HasPrefix(input[i:], "!="):
+			tokens = append(tokens, token{kind: "op", value: input[i : i+2]})
+			i += 2
+		case strings.ContainsRune("|.(),", c):
+			tokens = append(tokens, token{kind: "op", value: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", c, input)
+		}
+	}
+	return append(tokens, token{kind: "eof"}), nil
+}
+
+type exprParser struct {
+	input  string
+	tokens []token
+	pos    int
+}
+
+func parseExpr(input string) (expr, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{input: input, tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q in %q", p.peek().value, input)
+	}
+	return e, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(kind, value string) bool {
+	if t := p.peek(); t.kind == kind && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("ident", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("ident", "and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (expr, error) {
+	if p.accept("ident", "not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (expr, error) {
+	left, err := p.parseFiltered()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.accept("ident", "is") {
+		negate := p.accept("ident", "not")
+		if !p.accept("ident", "defined") {
+			if !p.accept("ident", "undefined") {
+				return nil, fmt.Errorf("only the defined and undefined tests are supported in %q", p.input)
+			}
+			negate = !negate
+		}
+		return &definedExpr{operand: left, negate: negate}, nil
+	}
+
+	for _, op := range []string{"==", "!="} {
+		if p.accept("op", op) {
+			right, err := p.parseFiltered()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryExpr{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseFiltered() (expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("op", "|") {
+		name := p.next()
+		if name.kind != "ident" {
+			return nil, fmt.Errorf("expected a filter name in %q", p.input)
+		}
+		f := &filterExpr{operand: e, name: name.value}
+		if p.accept("op", "(") {
+			for !p.accept("op", ")") {
+				arg, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				f.args = append(f.args, arg)
+				if !p.accept("op", ",") && p.peek().value != ")" {
+					return nil, fmt.Errorf("expected , or ) in %q", p.input)
+				}
+			}
+		}
+		e = f
+	}
+	return e, nil
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case "string":
+		return &literal{value: t.value}, nil
+	case "number":
+		if strings.Contains(t.value, ".") {
+			f, err := strconv.ParseFloat(t.value, 64)
+			return &literal{value: f}, err
+		}
+		i, err := strconv.ParseInt(t.value, 10, 64)
+		return &literal{value: i}, err
+	case "ident":
+		switch t.value {
+		case "true", "True":
+			return &literal{value: true}, nil
+		case "false", "False":
+			return &literal{value: false}, nil
+		case "none", "None":
+			return &literal{value: nil}, nil
+		}
+		v := &variable{path: []string{t.value}}
+		for p.accept("op", ".") {
+			key := p.next()
+			if key.kind != "ident" {
+				return nil, fmt.Errorf("expected an attribute name in %q", p.input)
+			}
+			v.path = append(v.path, key.value)
+		}
+		return v, nil
+	case "op":
+		if t.value == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept("op", ")") {
+				return nil, fmt.Errorf("expected ) in %q", p.input)
+			}
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q in %q", t.value, p.input)
+}
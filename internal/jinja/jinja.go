@@ -0,0 +1,315 @@
+// Package jinja renders the subset of Jinja used by the operator-pipelines Ansible role templates.
+//
+// Supported are {{ expressions }}, {# comments #}, {% raw %} blocks and {% if %}/{% elif %}/{% else %} blocks.
+// Expressions can use variables with dotted access, string, number and boolean literals, the comparison operators
+// == and !=, and, or, not, the "is defined" test and the filters listed in filters. Like Ansible, a newline
+// following a {% %} tag is removed and undefined variables are an error unless a default is given.
+package jinja
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDepth bounds how deeply variables whose values are themselves templates are rendered.
+const maxDepth = 10
+
+// Render renders the template with the given variables. String variables containing template syntax are
+// rendered when they are used, as Ansible does.
+func Render(template string, vars map[string]interface{}) (string, error) {
+	return render(template, &scope{vars: vars})
+}
+
+func render(template string, s *scope) (string, error) {
+	if s.depth > maxDepth {
+		return "", fmt.Errorf("variables are nested more than %d levels deep", maxDepth)
+	}
+
+	p := &parser{input: template}
+	nodes, end, err := p.parseNodes()
+	if err != nil {
+		return "", err
+	}
+	if end != nil {
+		return "", fmt.Errorf("unexpected {%% %s %%}", end.name)
+	}
+
+	var out strings.Builder
+	for _, n := range nodes {
+		if err := n.render(&out, s); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// isTemplate returns whether the string contains template syntax that needs rendering.
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{{") || strings.Contains(s, "{%") || strings.Contains(s, "{#")
+}
+
+type scope struct {
+	vars  map[string]interface{}
+	depth int
+}
+
+// lookup returns the value of the variable, rendering it first if it is a template.
+func (s *scope) lookup(name string) (interface{}, bool, error) {
+	value, ok := s.vars[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if str, isString := value.(string); isString && isTemplate(str) {
+		rendered, err := render(str, &scope{vars: s.vars, depth: s.depth + 1})
+		if err != nil && s.depth == 0 {
+			return nil, false, fmt.Errorf("variable %s: %w", name, err)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return rendered, true, nil
+	}
+
+	return value, true, nil
+}
+
+type node interface {
+	render(out *strings.Builder, s *scope) error
+}
+
+type textNode string
+
+func (n textNode) render(out *strings.Builder, _ *scope) error {
+	out.WriteString(string(n))
+	return nil
+}
+
+type exprNode struct {
+	expr expr
+}
+
+func (n *exprNode) render(out *strings.Builder, s *scope) error {
+	value, err := n.expr.eval(s)
+	if err != nil {
+		return err
+	}
+	if u, ok := value.(undefined); ok {
+		return fmt.Errorf("%s is undefined", u.name)
+	}
+	out.WriteString(toString(value))
+	return nil
+}
+
+type ifBranch struct {
+	cond expr
+	body []node
+}
+
+type ifNode struct {
+	branches []ifBranch
+	orElse   []node
+}
+
+func (n *ifNode) render(out *strings.Builder, s *scope) error {
+	body := n.orElse
+	for _, branch := range n.branches {
+		value, err := branch.cond.eval(s)
+		if err != nil {
+			return err
+		}
+		if truthy(value) {
+			body = branch.body
+			break
+		}
+	}
+
+	for _, child := range body {
+		if err := child.render(out, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tag is a {% %} statement, name is its first word and args the rest.
+type tag struct {
+	name string
+	args string
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+// parseNodes parses until the end of the input or a tag that closes a block, which is returned.
+func (p *parser) parseNodes() ([]node, *tag, error) {
+	var nodes []node
+	for p.pos < len(p.input) {
+		start := strings.Index(p.input[p.pos:], "{")
+		for start >= 0 {
+			next := p.pos + start
+			if next+1 < len(p.input) && strings.ContainsRune("{%#", rune(p.input[next+1])) {
+				break
+			}
+			following := strings.Index(p.input[next+1:], "{")
+			if following < 0 {
+				start = -1
+				break
+			}
+			start += following + 1
+		}
+
+		if start < 0 {
+			nodes = append(nodes, textNode(p.input[p.pos:]))
+			p.pos = len(p.input)
+			break
+		}
+
+		text := p.input[p.pos : p.pos+start]
+		p.pos += start
+		opener := p.input[p.pos : p.pos+2]
+		closer := map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}[opener]
+
+		end := strings.Index(p.input[p.pos+2:], closer)
+		if end < 0 {
+			return nil, nil, fmt.Errorf("unclosed %s at offset %d", opener, p.pos)
+		}
+		content := p.input[p.pos+2 : p.pos+2+end]
+		p.pos += 2 + end + 2
+
+		// Whitespace control: {{- and {%- trim the text before, -}} and -%} the text after
+		if strings.HasPrefix(content, "-") {
+			text = strings.TrimRight(text, " \t\r\n")
+			content = content[1:]
+		}
+		trimAfter := strings.HasSuffix(content, "-")
+		if trimAfter {
+			content = content[:len(content)-1]
+		}
+		if len(text) > 0 {
+			nodes = append(nodes, textNode(text))
+		}
+
+		switch opener {
+		case "{#":
+		case "{{":
+			e, err := parseExpr(content)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, &exprNode{expr: e})
+		case "{%":
+			p.trimBlock(trimAfter)
+			fields := strings.Fields(content)
+			if len(fields) == 0 {
+				return nil, nil, fmt.Errorf("empty {%% %%} tag")
+			}
+			t := &tag{name: fields[0], args: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(content), fields[0]))}
+			switch t.name {
+			case "raw":
+				n, err := p.parseRaw()
+				if err != nil {
+					return nil, nil, err
+				}
+				nodes = append(nodes, n)
+			case "if":
+				n, err := p.parseIf(t)
+				if err != nil {
+					return nil, nil, err
+				}
+				nodes = append(nodes, n)
+			case "elif", "else", "endif":
+				return nodes, t, nil
+			default:
+				return nil, nil, fmt.Errorf("unsupported {%% %s %%} tag", t.name)
+			}
+			continue
+		}
+
+		if trimAfter {
+			p.trimBlock(true)
+		}
+	}
+
+	return nodes, nil, nil
+}
+
+// trimBlock removes the newline following a tag, like Ansible's trim_blocks, or all the whitespace when the
+// tag asked for it.
+func (p *parser) trimBlock(all bool) {
+	if all {
+		for p.pos < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.pos])) {
+			p.pos++
+		}
+		return
+	}
+	if strings.HasPrefix(p.input[p.pos:], "\r\n") {
+		p.pos += 2
+	} else if strings.HasPrefix(p.input[p.pos:], "\n") {
+		p.pos++
+	}
+}
+
+func (p *parser) parseRaw() (node, error) {
+	for offset := p.pos; ; {
+		start := strings.Index(p.input[offset:], "{%")
+		if start < 0 {
+			return nil, fmt.Errorf("{%% raw %%} is not closed by {%% endraw %%}")
+		}
+		start += offset
+		end := strings.Index(p.input[start:], "%}")
+		if end < 0 {
+			return nil, fmt.Errorf("{%% raw %%} is not closed by {%% endraw %%}")
+		}
+		end += start
+
+		content := strings.Trim(p.input[start+2:end], "- \t")
+		if content == "endraw" {
+			text := p.input[p.pos:start]
+			if strings.HasPrefix(p.input[start+2:], "-") {
+				text = strings.TrimRight(text, " \t\r\n")
+			}
+			p.pos = end + 2
+			p.trimBlock(strings.HasSuffix(p.input[:end], "-"))
+			return textNode(text), nil
+		}
+		offset = end + 2
+	}
+}
+
+func (p *parser) parseIf(t *tag) (node, error) {
+	n := &ifNode{}
+	for {
+		cond, err := parseExpr(t.args)
+		if err != nil {
+			return nil, err
+		}
+		body, end, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		if end == nil {
+			return nil, fmt.Errorf("{%% if %s %%} is not closed by {%% endif %%}", t.args)
+		}
+		n.branches = append(n.branches, ifBranch{cond: cond, body: body})
+
+		switch end.name {
+		case "endif":
+			return n, nil
+		case "elif":
+			t = end
+		case "else":
+			orElse, last, err := p.parseNodes()
+			if err != nil {
+				return nil, err
+			}
+			if last == nil || last.name != "endif" {
+				return nil, fmt.Errorf("{%% else %%} is not closed by {%% endif %%}")
+			}
+			n.orElse = orElse
+			return n, nil
+		}
+	}
+}
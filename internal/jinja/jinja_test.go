@@ -0,0 +1,231 @@
+package jinja
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestRenderGolden renders templates laid out like the operator-pipeline Ansible role of operator-pipelines, with
+// the variables of its defaults, and compares them to the .golden files next to them. Run with -update to
+// regenerate the golden files after a deliberate change.
+func TestRenderGolden(t *testing.T) {
+	root := filepath.Join("testdata", "operator-pipeline")
+	b, err := os.ReadFile(filepath.Join(root, "defaults.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &vars); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := filepath.Glob(filepath.Join(root, "templates", "*.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("no templates found")
+	}
+	for _, template := range templates {
+		t.Run(filepath.Base(template), func(t *testing.T) {
+			b, err := os.ReadFile(template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Render(string(b), vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := strings.TrimSuffix(template, ".yml") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Fatalf("rendered %s differs from %s:\n%s", template, golden, got)
+			}
+
+			// The rendered manifests must still be valid YAML
+			if err := yaml.Unmarshal([]byte(got), &map[string]interface{}{}); err != nil {
+				t.Fatalf("rendered %s is not valid YAML: %v", template, err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	vars := map[string]interface{}{
+		"name":      "operator-ci",
+		"namespace": "Pipelines",
+		"enabled":   true,
+		"disabled":  false,
+		"empty":     "",
+		"count":     int64(3),
+		"padded":    "  spaced  ",
+		"tag":       "v1.2",
+		"image": map[string]interface{}{
+			"registry": "quay.io",
+			"name":     "redhat-isv/operator-pipelines-images",
+		},
+		"pull_spec": "{{ image.registry }}/{{ image.name }}:{{ tag }}",
+		"nested":    "{{ pull_spec }}",
+		"labels":    map[string]interface{}{"app": "ci"},
+		"env":       "prod",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		// Expressions
+		{"text", "plain text", "plain text"},
+		{"variable", "name: {{ name }}", "name: operator-ci"},
+		{"dotted access", "{{ image.registry }}", "quay.io"},
+		{"string literal", `{{ 'single' }} {{ "double" }}`, "single double"},
+		{"number literals", "{{ 42 }} {{ 1.5 }}", "42 1.5"},
+		{"boolean literals", "{{ true }} {{ False }} {{ none }}", "True False None"},
+		{"boolean variable", "{{ enabled }}", "True"},
+		{"equality", "{{ name == 'operator-ci' }} {{ name != 'operator-ci' }}", "True False"},
+		{"equality across types", "{{ count == '3' }}", "True"},
+		{"and or", "{{ enabled and name }} {{ disabled or 'fallback' }}", "operator-ci fallback"},
+		{"not", "{{ not enabled }} {{ not empty }}", "False True"},
+		{"parentheses", "{{ not (disabled or empty) }}", "True"},
+		{"variable holding a template", "{{ pull_spec }}", "quay.io/redhat-isv/operator-pipelines-images:v1.2"},
+		{"nested variable templates", "{{ nested }}", "quay.io/redhat-isv/operator-pipelines-images:v1.2"},
+		{"lone braces", "a { b } {c}", "a { b } {c}"},
+		{"comment", "a{# ignored {{ missing }} #}b", "ab"},
+
+		// if / elif / else
+		{"if true", "{% if enabled %}yes{% endif %}", "yes"},
+		{"if false", "{% if disabled %}yes{% endif %}", ""},
+		{"else", "{% if disabled %}yes{% else %}no{% endif %}", "no"},
+		{"elif first", "{% if env == 'prod' %}p{% elif env == 'stage' %}s{% else %}d{% endif %}", "p"},
+		{"elif second", "{% if env == 'dev' %}d{% elif env == 'prod' %}p{% else %}x{% endif %}", "p"},
+		{"elif none", "{% if env == 'dev' %}d{% elif env == 'stage' %}s{% else %}x{% endif %}", "x"},
+		{"elif without else", "{% if disabled %}a{% elif empty %}b{% endif %}", ""},
+		{"nested if", "{% if enabled %}{% if disabled %}a{% else %}b{% endif %}{% endif %}", "b"},
+		{"is defined", "{% if name is defined %}set{% endif %}", "set"},
+		{"is not defined", "{% if missing is not defined %}unset{% endif %}", "unset"},
+		{"is undefined", "{% if missing is undefined %}unset{% endif %}", "unset"},
+		{"undefined is falsy", "{% if missing %}set{% else %}unset{% endif %}", "unset"},
+		{"undefined attribute is falsy", "{% if image.tag %}set{% else %}unset{% endif %}", "unset"},
+		{"and short circuits undefined", "{% if missing is defined and missing == 'x' %}x{% endif %}", ""},
+
+		// Ansible's trim_blocks removes the newline following a block tag
+		{"trim blocks", "{% if enabled %}\nline\n{% endif %}\nafter", "line\nafter"},
+		{"trim blocks crlf", "{% if enabled %}\r\nline\r\n{% endif %}\r\n", "line\r\n"},
+		{"expressions keep their newline", "{{ name }}\nnext", "operator-ci\nnext"},
+
+		// Whitespace control
+		{"trim before expression", "a   \n  {{- name }}", "aoperator-ci"},
+		{"trim after expression", "{{ name -}}  \n  b", "operator-cib"},
+		{"trim both sides of a block", "a \n {%- if enabled -%} \n b \n {%- endif -%} \n c", "abc"},
+		{"trim else", "{% if disabled %}a{% else -%}   \n  b{%- endif %}", "b"},
+		{"trim comment", "a  {#- note -#}  b", "ab"},
+
+		// raw blocks
+		{"raw", "{% raw %}{{ .Digest }}{% endraw %}", "{{ .Digest }}"},
+		{"raw keeps tags", "{% raw %}{% if x %}{# c #}{% endraw %}", "{% if x %}{# c #}"},
+		{"raw trims block newline", "{% raw %}\n{{ x }}\n{% endraw %}\nafter", "{{ x }}\nafter"},
+		{"raw whitespace control", "a {%- raw -%}  {{ x }}  {%- endraw -%}  b", "a{{ x }}b"},
+		{"raw among expressions", "{{ name }} {% raw %}{{ name }}{% endraw %}", "operator-ci {{ name }}"},
+
+		// Filters
+		{"default on undefined", "{{ missing | default('fallback') }}", "fallback"},
+		{"d alias", "{{ missing | d('fallback') }}", "fallback"},
+		{"default keeps defined", "{{ empty | default('fallback') }}", ""},
+		{"default on falsy", "{{ empty | default('fallback', true) }}", "fallback"},
+		{"default with variable", "{{ missing | default(name) }}", "operator-ci"},
+		{"default undefined attribute", "{{ image.tag | default('latest') }}", "latest"},
+		{"lower", "{{ namespace | lower }}", "pipelines"},
+		{"upper", "{{ name | upper }}", "OPERATOR-CI"},
+		{"trim", "[{{ padded | trim }}]", "[spaced]"},
+		{"string", "{{ count | string }}", "3"},
+		{"int", "{{ '7' | int }} {{ '2.9' | int }} {{ 'x' | int }}", "7 2 0"},
+		{"bool", "{{ 'yes' | bool }} {{ 'off' | bool }}", "True False"},
+		{"replace", "{{ name | replace('-', '_') }}", "operator_ci"},
+		{"to_json", "{{ labels | to_json }}", `{"app":"ci"}`},
+		{"chained filters", "{{ missing | default(' MiXeD ') | trim | lower }}", "mixed"},
+		{"filter in condition", "{% if env | upper == 'PROD' %}p{% endif %}", "p"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.template, vars)
+			if err != nil {
+				t.Fatalf("Render(%q): %v", tt.template, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	vars := map[string]interface{}{
+		"name":      "operator-ci",
+		"image":     map[string]interface{}{"registry": "quay.io"},
+		"broken":    "{{ missing }}",
+		"recursive": "{{ recursive }}",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		// Undefined variables
+		{"undefined variable", "{{ missing }}", "missing is undefined"},
+		{"undefined attribute", "{{ image.tag }}", "image.tag is undefined"},
+		{"attribute of a string", "{{ name.first }}", "name.first is undefined"},
+		{"undefined in comparison", "{% if missing == 'x' %}x{% endif %}", "missing is undefined"},
+		{"undefined through a filter", "{{ missing | lower }}", "missing is undefined"},
+		{"undefined returned by or", "{{ missing or other }}", "other is undefined"},
+		{"undefined inside a variable", "{{ broken }}", "variable broken: missing is undefined"},
+		{"recursive variable", "{{ recursive }}", "nested more than"},
+
+		// Syntax
+		{"unclosed expression", "{{ name", "unclosed {{"},
+		{"unclosed tag", "{% if name", "unclosed {%"},
+		{"unclosed comment", "{# note", "unclosed {#"},
+		{"unclosed if", "{% if name %}x", "not closed by {% endif %}"},
+		{"unclosed else", "{% if name %}x{% else %}y", "not closed by {% endif %}"},
+		{"unclosed raw", "{% raw %}{{ x }}", "not closed by {% endraw %}"},
+		{"endif without if", "x{% endif %}", "unexpected {% endif %}"},
+		{"else after else", "{% if name %}a{% else %}b{% else %}c{% endif %}", "not closed by {% endif %}"},
+		{"empty tag", "{% %}", "empty {% %} tag"},
+		{"unsupported tag", "{% for x in y %}{% endfor %}", "unsupported {% for %} tag"},
+		{"unsupported filter", "{{ name | title }}", "unsupported filter title"},
+		{"unsupported test", "{% if name is string %}x{% endif %}", "only the defined and undefined tests"},
+		{"unterminated string", "{{ 'open }}", "unterminated string"},
+		{"unexpected character", "{{ name + 1 }}", "unexpected '+'"},
+		{"trailing tokens", "{{ name name }}", `unexpected "name"`},
+		{"replace arguments", "{{ name | replace('-') }}", "replace expects 2 arguments"},
+		{"default arguments", "{{ name | default }}", "default expects 1 or 2 arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.template, vars)
+			if err == nil {
+				t.Fatalf("Render(%q) = %q, want an error containing %q", tt.template, got, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Render(%q): %v, want an error containing %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
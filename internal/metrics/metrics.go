@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// FetchResultUpdated means new objects were fetched from the remote
+	FetchResultUpdated = "updated"
+	// FetchResultUpToDate means the remote had nothing new to fetch
+	FetchResultUpToDate = "up_to_date"
+	// FetchResultCached means the fetch was skipped because the local repository was recent enough
+	FetchResultCached = "cached"
+	// FetchResultError means the fetch failed
+	FetchResultError = "error"
+
+	// RunResultSucceeded labels the PipelineRuns that succeeded
+	RunResultSucceeded = "succeeded"
+	// RunResultFailed labels the PipelineRuns that failed or were cancelled
+	RunResultFailed = "failed"
+)
+
+var (
+	// GitFetchDuration observes how long fetching the operator-pipelines repository takes
+	GitFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "operator_certification_git_fetch_duration_seconds",
+		Help:    "Duration of operator-pipelines repository clones and fetches.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"remote", "result"})
+
+	// GitFetchTotal counts the operator-pipelines repository lookups by result, including the ones served from cache
+	GitFetchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "operator_certification_git_fetch_total",
+		Help: "Number of operator-pipelines repository lookups by result.",
+	}, []string{"remote", "result"})
+
+	// PipelineRunsPrunedTotal counts the PipelineRuns deleted by the retention policy of the OperatorPipelines
+	PipelineRunsPrunedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "operator_certification_pipelineruns_pruned_total",
+		Help: "Number of PipelineRuns pruned by the retention policy.",
+	}, []string{"namespace", "operator_pipeline", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(GitFetchDuration, GitFetchTotal, PipelineRunsPrunedTotal)
+}
@@ -0,0 +1,32 @@
+package preflight
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Parse decodes a preflight JSON report. It returns false when the value is not one, so any result can be tried.
+func Parse(value string) (*Results, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		return nil, false
+	}
+
+	results := &Results{}
+	if err := json.Unmarshal([]byte(value), results); err != nil {
+		return nil, false
+	}
+	// Any JSON object decodes, a report names the test library and has checks
+	checks := len(results.Results.Passed) + len(results.Results.Failed) + len(results.Results.Errors)
+	if len(results.TestLibrary.Name) == 0 || checks == 0 {
+		return nil, false
+	}
+	return results, true
+}
+
+// Digest returns the digest of the image a report was run against, empty when it was referenced by tag.
+func (r *Results) Digest() string {
+	if i := strings.LastIndex(r.Image, "@"); i >= 0 {
+		return r.Image[i+1:]
+	}
+	return ""
+}
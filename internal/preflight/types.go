@@ -0,0 +1,33 @@
+package preflight
+
+// Results is the JSON report preflight writes at the end of a check run
+type Results struct {
+	Image             string      `json:"image"`
+	Passed            bool        `json:"passed"`
+	CertificationHash string      `json:"certification_hash,omitempty"`
+	TestLibrary       TestLibrary `json:"test_library"`
+	Results           Checks      `json:"results"`
+}
+
+type TestLibrary struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// Checks are the checks of a report grouped by outcome. Errors are the checks that could not be run.
+type Checks struct {
+	Passed []Check `json:"passed"`
+	Failed []Check `json:"failed"`
+	Errors []Check `json:"errors"`
+}
+
+type Check struct {
+	Name             string  `json:"name"`
+	ElapsedTime      float64 `json:"elapsed_time"`
+	Description      string  `json:"description"`
+	Help             string  `json:"help,omitempty"`
+	Suggestion       string  `json:"suggestion,omitempty"`
+	KnowledgebaseURL string  `json:"knowledgebase_url,omitempty"`
+	CheckURL         string  `json:"check_url,omitempty"`
+}
@@ -0,0 +1,287 @@
+package reconcilers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/preflight"
+
+	tektonpipeline "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// CertificationReportLabel is set on the report ConfigMaps, with the name of their PipelineRun
+	CertificationReportLabel = "certification.redhat.com/certification-report"
+	// PreflightLabel is set on the report ConfigMaps with a preflight report, to passed or failed
+	PreflightLabel = "certification.redhat.com/preflight"
+	// CertificationReportKey is the key of the report ConfigMaps holding the report in JSON
+	CertificationReportKey = "report.json"
+
+	// preflightReportFileResult is the result of the preflight tasks of operator-pipelines with the path of their
+	// JSON report in their output workspace
+	preflightReportFileResult = "result_output_file"
+	preflightOutputWorkspace  = "output"
+
+	CheckPassed = "passed"
+	CheckFailed = "failed"
+	CheckError  = "error"
+)
+
+// bundleDigestResults are the results holding the digest of the bundle image, in order of preference. IMAGE_DIGEST
+// is the result of the task building the bundle.
+var bundleDigestResults = []string{"bundle_digest", "bundle_image_digest", "IMAGE_DIGEST"}
+
+// CertificationReport summarizes a completed PipelineRun of a pipeline installed by the operator
+type CertificationReport struct {
+	PipelineRun    string       `json:"pipelineRun"`
+	Pipeline       string       `json:"pipeline"`
+	Succeeded      bool         `json:"succeeded"`
+	Reason         string       `json:"reason,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	OCPVersion     string       `json:"ocpVersion,omitempty"`
+	BundleDigest   string       `json:"bundleDigest,omitempty"`
+	// Preflight is the preflight report found in the TaskRun results or in the workspace of the preflight task, if
+	// any.
+	Preflight *PreflightReport `json:"preflight,omitempty"`
+	// PreflightFile is the file of the workspace of the preflight task the preflight report was read from
+	PreflightFile string `json:"preflightFile,omitempty"`
+	// PreflightNotice tells why there is no preflight report although a preflight task ran
+	PreflightNotice string       `json:"preflightNotice,omitempty"`
+	Tasks           []TaskReport `json:"tasks"`
+}
+
+// PreflightReport lists the outcome of every preflight check
+type PreflightReport struct {
+	Task   string        `json:"task"`
+	Image  string        `json:"image"`
+	Passed bool          `json:"passed"`
+	Checks []CheckReport `json:"checks"`
+}
+
+type CheckReport struct {
+	Name        string `json:"name"`
+	Result      string `json:"result"`
+	Description string `json:"description,omitempty"`
+	Help        string `json:"help,omitempty"`
+	Suggestion  string `json:"suggestion,omitempty"`
+}
+
+// TaskReport is the outcome of a TaskRun of the PipelineRun with its results. Results holding the preflight report
+// are summarized in the preflight report instead.
+type TaskReport struct {
+	Name      string            `json:"name"`
+	TaskRun   string            `json:"taskRun"`
+	Succeeded bool              `json:"succeeded"`
+	Reason    string            `json:"reason,omitempty"`
+	Results   map[string]string `json:"results,omitempty"`
+}
+
+// NewCertificationReport summarizes a PipelineRun and its TaskRuns. The preflight report is looked up in the
+// TaskRun results, any result holding a preflight JSON report is used. The preflight tasks of operator-pipelines
+// write their report to their workspace instead, PreflightWorkspaceFile tells where to read it from.
+func NewCertificationReport(pr *tekton.PipelineRun, taskRuns []tekton.TaskRun) (*CertificationReport, error) {
+	report := &CertificationReport{
+		PipelineRun:    pr.Name,
+		Pipeline:       pr.Labels[tektonpipeline.PipelineLabelKey],
+		CompletionTime: pr.Status.CompletionTime,
+		OCPVersion:     pr.Labels[OCPVersionLabel],
+		Tasks:          []TaskReport{},
+	}
+	if condition := pr.Status.GetCondition(apis.ConditionSucceeded); condition != nil {
+		report.Succeeded = condition.IsTrue()
+		report.Reason = condition.Reason
+	}
+	if len(report.OCPVersion) == 0 {
+		for _, param := range pr.Spec.Params {
+			if param.Name == "ocp_version" {
+				report.OCPVersion = param.Value.StringVal
+			}
+		}
+	}
+
+	var preflightResults *preflight.Results
+	var preflightTask string
+	digests := map[string]string{}
+	for _, result := range pr.Status.Results {
+		digests[result.Name] = result.Value.StringVal
+	}
+
+	for _, tr := range taskRuns {
+		task := TaskReport{Name: tr.Labels[tektonpipeline.PipelineTaskLabelKey], TaskRun: tr.Name, Results: map[string]string{}}
+		if condition := tr.Status.GetCondition(apis.ConditionSucceeded); condition != nil {
+			task.Succeeded = condition.IsTrue()
+			task.Reason = condition.Reason
+		}
+
+		for _, result := range tr.Status.Results {
+			value, err := ResultValue(result.Value)
+			if err != nil {
+				return nil, err
+			}
+			if results, ok := preflight.Parse(value); ok {
+				preflightResults = results
+				report.Preflight = newPreflightReport(task.Name, results)
+				continue
+			}
+			task.Results[result.Name] = value
+			if _, ok := digests[result.Name]; !ok {
+				digests[result.Name] = value
+			}
+		}
+		report.Tasks = append(report.Tasks, task)
+		if len(preflightTask) == 0 && strings.Contains(task.Name, "preflight") {
+			preflightTask = task.Name
+		}
+	}
+
+	if report.Preflight == nil && len(preflightTask) > 0 {
+		report.PreflightNotice = fmt.Sprintf("Task %s published no preflight JSON report in its results", preflightTask)
+	}
+
+	for _, name := range bundleDigestResults {
+		if len(digests[name]) > 0 {
+			report.BundleDigest = digests[name]
+			break
+		}
+	}
+	if len(report.BundleDigest) == 0 && preflightResults != nil {
+		report.BundleDigest = preflightResults.Digest()
+	}
+
+	return report, nil
+}
+
+// SetWorkspacePreflight sets the preflight report from the content of the report file a task wrote to its
+// workspace. The notice tells when the file is not a preflight JSON report.
+func (r *CertificationReport) SetWorkspacePreflight(file *WorkspaceFile, value string) {
+	results, ok := preflight.Parse(value)
+	if !ok {
+		r.PreflightNotice = fmt.Sprintf("File %s written by task %s is not a preflight JSON report", file.Path, file.Task)
+		return
+	}
+	r.Preflight = newPreflightReport(file.Task, results)
+	r.PreflightFile = file.Path
+	r.PreflightNotice = ""
+	if len(r.BundleDigest) == 0 {
+		r.BundleDigest = results.Digest()
+	}
+}
+
+// SetExistingPreflight sets the preflight report from the report ConfigMap when it was already read from the file.
+// It returns false when the file still has to be read.
+func (r *CertificationReport) SetExistingPreflight(cm *corev1.ConfigMap, file *WorkspaceFile) bool {
+	existing := &CertificationReport{}
+	if err := json.Unmarshal([]byte(cm.Data[CertificationReportKey]), existing); err != nil || existing.PreflightFile != file.Path {
+		return false
+	}
+	r.Preflight = existing.Preflight
+	r.PreflightFile = existing.PreflightFile
+	r.PreflightNotice = existing.PreflightNotice
+	if len(r.BundleDigest) == 0 {
+		r.BundleDigest = existing.BundleDigest
+	}
+	return true
+}
+
+// WorkspaceFile is a file a TaskRun wrote to a workspace backed by a claim
+type WorkspaceFile struct {
+	// Task is the pipeline task that wrote the file
+	Task string
+	// ClaimName is the claim of the workspace and Path the path of the file in the claim
+	ClaimName string
+	Path      string
+	// Image is the image of the last step of the TaskRun, it is already pulled and can print the file
+	Image string
+}
+
+// PreflightWorkspaceFile returns where the preflight task of the TaskRuns wrote its JSON report, nil when no
+// preflight task names its report file or its workspace is not backed by a claim.
+func PreflightWorkspaceFile(taskRuns []tekton.TaskRun) *WorkspaceFile {
+	for _, tr := range taskRuns {
+		task := tr.Labels[tektonpipeline.PipelineTaskLabelKey]
+		if !strings.Contains(task, "preflight") || tr.Status.TaskSpec == nil || len(tr.Status.TaskSpec.Steps) == 0 {
+			continue
+		}
+
+		var file string
+		for _, result := range tr.Status.Results {
+			if result.Name == preflightReportFileResult {
+				file = result.Value.StringVal
+			}
+		}
+		// The path is relative to the workspace, it cannot leave it
+		file = path.Clean(file)
+		if file == "." || !fs.ValidPath(file) {
+			continue
+		}
+
+		var workspace *tekton.WorkspaceBinding
+		for i := range tr.Spec.Workspaces {
+			w := &tr.Spec.Workspaces[i]
+			if w.PersistentVolumeClaim != nil && (workspace == nil || w.Name == preflightOutputWorkspace) {
+				workspace = w
+			}
+		}
+		if workspace == nil {
+			continue
+		}
+
+		steps := tr.Status.TaskSpec.Steps
+		return &WorkspaceFile{
+			Task:      task,
+			ClaimName: workspace.PersistentVolumeClaim.ClaimName,
+			Path:      path.Join(workspace.SubPath, file),
+			Image:     steps[len(steps)-1].Image,
+		}
+	}
+	return nil
+}
+
+// ConfigMapData returns the data of the report ConfigMap.
+func (r *CertificationReport) ConfigMapData() (map[string]string, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{CertificationReportKey: string(b)}, nil
+}
+
+func newPreflightReport(task string, results *preflight.Results) *PreflightReport {
+	report := &PreflightReport{Task: task, Image: results.Image, Passed: results.Passed, Checks: []CheckReport{}}
+	for _, group := range []struct {
+		result string
+		checks []preflight.Check
+	}{
+		{CheckFailed, results.Results.Failed},
+		{CheckError, results.Results.Errors},
+		{CheckPassed, results.Results.Passed},
+	} {
+		for _, check := range group.checks {
+			report.Checks = append(report.Checks, CheckReport{
+				Name:        check.Name,
+				Result:      group.result,
+				Description: check.Description,
+				Help:        check.Help,
+				Suggestion:  check.Suggestion,
+			})
+		}
+	}
+	// Failures and errors come first, each group sorted by check name
+	order := map[string]int{CheckFailed: 0, CheckError: 1, CheckPassed: 2}
+	sort.SliceStable(report.Checks, func(i, j int) bool {
+		a, b := report.Checks[i], report.Checks[j]
+		if a.Result != b.Result {
+			return order[a.Result] < order[b.Result]
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
@@ -0,0 +1,176 @@
+package reconcilers
+
+import (
+	"reflect"
+	"testing"
+
+	tektonpipeline "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testPreflightReport = `{
+  "image": "quay.io/example/bundle@sha256:0123",
+  "passed": false,
+  "test_library": {"name": "github.com/redhat-openshift-ecosystem/openshift-preflight", "version": "1.9.0"},
+  "results": {
+    "passed": [{"name": "ValidateOperatorBundle", "description": "Validating Bundle image"}],
+    "failed": [{"name": "DeployableByOLM", "description": "Checking if the operator could be deployed by OLM"}],
+    "errors": []
+  }
+}`
+
+func testTaskRun(task string, results map[string]string) tekton.TaskRun {
+	tr := tekton.TaskRun{ObjectMeta: metav1.ObjectMeta{
+		Name:   "run-" + task,
+		Labels: map[string]string{tektonpipeline.PipelineTaskLabelKey: task},
+	}}
+	for name, value := range results {
+		tr.Status.Results = append(tr.Status.Results, tekton.TaskRunResult{Name: name, Value: *tekton.NewStructuredValues(value)})
+	}
+	return tr
+}
+
+func TestNewCertificationReportPreflight(t *testing.T) {
+	tests := []struct {
+		name          string
+		taskRuns      []tekton.TaskRun
+		wantChecks    int
+		wantDigest    string
+		wantNotice    bool
+		wantPreflight bool
+	}{
+		{
+			name:          "report published as a result",
+			taskRuns:      []tekton.TaskRun{testTaskRun("run-preflight", map[string]string{"report": testPreflightReport})},
+			wantPreflight: true,
+			wantChecks:    2,
+			wantDigest:    "sha256:0123",
+		},
+		{
+			name:       "report only written to the workspace",
+			taskRuns:   []tekton.TaskRun{testTaskRun("run-preflight", map[string]string{"result": "failed"})},
+			wantNotice: true,
+		},
+		{
+			name:       "no preflight task",
+			taskRuns:   []tekton.TaskRun{testTaskRun("build-bundle", map[string]string{"IMAGE_DIGEST": "sha256:4567"})},
+			wantDigest: "sha256:4567",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := &tekton.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "run"}}
+			report, err := NewCertificationReport(pr, tt.taskRuns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (report.Preflight != nil) != tt.wantPreflight {
+				t.Fatalf("preflight report %+v, want one %v", report.Preflight, tt.wantPreflight)
+			}
+			if report.Preflight != nil && len(report.Preflight.Checks) != tt.wantChecks {
+				t.Fatalf("preflight checks %+v, want %d", report.Preflight.Checks, tt.wantChecks)
+			}
+			if (len(report.PreflightNotice) > 0) != tt.wantNotice {
+				t.Fatalf("preflight notice %q, want one %v", report.PreflightNotice, tt.wantNotice)
+			}
+			if report.BundleDigest != tt.wantDigest {
+				t.Fatalf("bundle digest %q, want %q", report.BundleDigest, tt.wantDigest)
+			}
+		})
+	}
+}
+
+func TestPreflightWorkspaceFile(t *testing.T) {
+	taskRun := func(task string, results map[string]string, workspaces ...tekton.WorkspaceBinding) tekton.TaskRun {
+		tr := tekton.TaskRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "run-" + task, Labels: map[string]string{tektonpipeline.PipelineTaskLabelKey: task}},
+			Spec:       tekton.TaskRunSpec{Workspaces: workspaces},
+		}
+		tr.Status.TaskSpec = &tekton.TaskSpec{Steps: []tekton.Step{{Image: "quay.io/example/setup"}, {Image: "quay.io/example/preflight"}}}
+		for name, value := range results {
+			tr.Status.Results = append(tr.Status.Results, tekton.TaskRunResult{Name: name, Value: *tekton.NewStructuredValues(value)})
+		}
+		return tr
+	}
+	output := tekton.WorkspaceBinding{
+		Name:                  "output",
+		SubPath:               "run",
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-0123"},
+	}
+	other := tekton.WorkspaceBinding{
+		Name:                  "source",
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-4567"},
+	}
+	credentials := tekton.WorkspaceBinding{Name: "credentials", Secret: &corev1.SecretVolumeSource{SecretName: "registry"}}
+
+	tests := []struct {
+		name     string
+		taskRuns []tekton.TaskRun
+		want     *WorkspaceFile
+	}{
+		{
+			name:     "report file in the output workspace",
+			taskRuns: []tekton.TaskRun{taskRun("run-preflight", map[string]string{"result_output_file": "preflight/results.json"}, other, output)},
+			want: &WorkspaceFile{
+				Task:      "run-preflight",
+				ClaimName: "pvc-0123",
+				Path:      "run/preflight/results.json",
+				Image:     "quay.io/example/preflight",
+			},
+		},
+		{
+			name:     "report file in the only claim",
+			taskRuns: []tekton.TaskRun{taskRun("run-preflight", map[string]string{"result_output_file": "results.json"}, credentials, other)},
+			want:     &WorkspaceFile{Task: "run-preflight", ClaimName: "pvc-4567", Path: "results.json", Image: "quay.io/example/preflight"},
+		},
+		{
+			name:     "no workspace backed by a claim",
+			taskRuns: []tekton.TaskRun{taskRun("run-preflight", map[string]string{"result_output_file": "results.json"}, credentials)},
+		},
+		{
+			name:     "report file outside the workspace",
+			taskRuns: []tekton.TaskRun{taskRun("run-preflight", map[string]string{"result_output_file": "../results.json"}, output)},
+		},
+		{
+			name:     "absolute report file",
+			taskRuns: []tekton.TaskRun{taskRun("run-preflight", map[string]string{"result_output_file": "/etc/passwd"}, output)},
+		},
+		{
+			name:     "no report file",
+			taskRuns: []tekton.TaskRun{taskRun("run-preflight", map[string]string{"result": "failed"}, output)},
+		},
+		{
+			name:     "not a preflight task",
+			taskRuns: []tekton.TaskRun{taskRun("build-bundle", map[string]string{"result_output_file": "results.json"}, output)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PreflightWorkspaceFile(tt.taskRuns)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("PreflightWorkspaceFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificationReportSetWorkspacePreflight(t *testing.T) {
+	file := &WorkspaceFile{Task: "run-preflight", ClaimName: "pvc-0123", Path: "run/results.json"}
+
+	report := &CertificationReport{PreflightNotice: "Task run-preflight published no preflight JSON report in its results"}
+	report.SetWorkspacePreflight(file, testPreflightReport)
+	if report.Preflight == nil || len(report.Preflight.Checks) != 2 || len(report.PreflightNotice) > 0 {
+		t.Fatalf("preflight report %+v, notice %q", report.Preflight, report.PreflightNotice)
+	}
+	if report.PreflightFile != file.Path || report.BundleDigest != "sha256:0123" {
+		t.Fatalf("preflight file %q, bundle digest %q", report.PreflightFile, report.BundleDigest)
+	}
+
+	report = &CertificationReport{}
+	report.SetWorkspacePreflight(file, "preflight: command not found")
+	if report.Preflight != nil || report.PreflightNotice != "File run/results.json written by task run-preflight is not a preflight JSON report" {
+		t.Fatalf("preflight report %+v, notice %q", report.Preflight, report.PreflightNotice)
+	}
+}
@@ -44,6 +44,11 @@ func NewCertifiedImageStreamReconciler(client client.Client, log logr.Logger, sc
 
 // reconcileCertifiedImageStream will ensure that the certified operator ImageStream is present and up to date.
 func (r *CertifiedImageStreamReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	// The status reconciler reports the image stream as missing, nothing is imported in ReportOnly mode
+	if reportOnly(pipeline) {
+		return false, nil
+	}
+
 	operatorIndices, err := r.pyxisClient.FindOperatorIndices(ctx, "certified-operators")
 	if err != nil {
 		return true, err
@@ -0,0 +1,132 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectDrift compares the live object with the desired one from the manifests. It returns nil when the live
+// object has every field of the manifest with the same value.
+func objectDrift(ctx context.Context, c client.Client, desired *unstructured.Unstructured, driftType v1alpha1.DriftType) (*v1alpha1.ObjectDrift, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKeyFromObject(desired), live)
+
+	drift := &v1alpha1.ObjectDrift{
+		Kind:      desired.GetKind(),
+		Name:      desired.GetName(),
+		Namespace: desired.GetNamespace(),
+	}
+
+	switch {
+	case errors.IsNotFound(err) && driftType == v1alpha1.DriftUnwanted:
+		return nil, nil
+	case errors.IsNotFound(err):
+		drift.Type = v1alpha1.DriftMissing
+		return drift, nil
+	case err != nil:
+		return nil, err
+	case driftType == v1alpha1.DriftUnwanted:
+		drift.Type = v1alpha1.DriftUnwanted
+		return drift, nil
+	}
+
+	drift.Fields = diffFields(desired.Object, live.Object)
+	if len(drift.Fields) == 0 {
+		return nil, nil
+	}
+	drift.Type = v1alpha1.DriftModified
+	return drift, nil
+}
+
+// diffFields returns the paths of the fields set in desired that have a different value in live. Fields only
+// set in live, such as defaults or fields managed by others, are not drift. Of the metadata, only the labels
+// and annotations are compared, and the status is ignored.
+func diffFields(desired, live map[string]interface{}) []string {
+	var diffs []string
+	for _, key := range sortedKeys(desired) {
+		switch key {
+		case "status":
+			continue
+		case "metadata":
+			desiredMeta, _ := desired[key].(map[string]interface{})
+			liveMeta, _ := live[key].(map[string]interface{})
+			for _, metaKey := range []string{"labels", "annotations"} {
+				if value, ok := desiredMeta[metaKey]; ok {
+					diffs = diffValue("metadata."+metaKey, value, liveMeta[metaKey], diffs)
+				}
+			}
+		default:
+			diffs = diffValue(key, desired[key], live[key], diffs)
+		}
+	}
+	return diffs
+}
+
+func diffValue(path string, desired, live interface{}, diffs []string) []string {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return append(diffs, path)
+		}
+		for _, key := range sortedKeys(desiredValue) {
+			diffs = diffValue(path+"."+key, desiredValue[key], liveValue[key], diffs)
+		}
+		return diffs
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok || len(liveValue) != len(desiredValue) {
+			return append(diffs, path)
+		}
+		for i := range desiredValue {
+			diffs = diffValue(fmt.Sprintf("%s[%d]", path, i), desiredValue[i], liveValue[i], diffs)
+		}
+		return diffs
+	default:
+		if !scalarEqual(desired, live) {
+			return append(diffs, path)
+		}
+		return diffs
+	}
+}
+
+// scalarEqual compares scalar values, numbers are compared by value since the manifests and the API server
+// do not always decode them to the same type.
+func scalarEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,114 @@
+package reconcilers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	desired := map[string]interface{}{
+		"apiVersion": "tekton.dev/v1",
+		"kind":       "Task",
+		"metadata": map[string]interface{}{
+			"name":   "preflight",
+			"labels": map[string]interface{}{"app": "operator-pipelines"},
+		},
+		"spec": map[string]interface{}{
+			"params": []interface{}{
+				map[string]interface{}{"name": "bundle_path", "default": "."},
+			},
+			"steps": []interface{}{
+				map[string]interface{}{"name": "preflight", "image": "quay.io/opdev/preflight:stable", "timeout": int64(300)},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		live map[string]interface{}
+		want []string
+	}{
+		{
+			name: "same fields",
+			live: desired,
+		},
+		{
+			name: "fields only set in the live object",
+			live: map[string]interface{}{
+				"apiVersion": "tekton.dev/v1",
+				"kind":       "Task",
+				"metadata": map[string]interface{}{
+					"name":            "preflight",
+					"uid":             "uid",
+					"resourceVersion": "42",
+					"labels":          map[string]interface{}{"app": "operator-pipelines", "team": "isv"},
+					"annotations":     map[string]interface{}{"kubectl.kubernetes.io/last-applied-configuration": "{}"},
+				},
+				"spec": map[string]interface{}{
+					"params": []interface{}{
+						map[string]interface{}{"name": "bundle_path", "default": ".", "type": "string"},
+					},
+					"steps": []interface{}{
+						// Numbers the API server decodes as float64
+						map[string]interface{}{"name": "preflight", "image": "quay.io/opdev/preflight:stable", "timeout": float64(300), "computeResources": map[string]interface{}{}},
+					},
+				},
+				"status": map[string]interface{}{"observedGeneration": int64(1)},
+			},
+		},
+		{
+			name: "changed, removed and retyped fields",
+			live: map[string]interface{}{
+				"apiVersion": "tekton.dev/v1",
+				"kind":       "Task",
+				"metadata": map[string]interface{}{
+					"name":   "preflight",
+					"labels": map[string]interface{}{"app": "edited"},
+				},
+				"spec": map[string]interface{}{
+					"params": "bundle_path",
+					"steps": []interface{}{
+						map[string]interface{}{"name": "preflight", "image": "quay.io/opdev/preflight:1.0.0"},
+					},
+				},
+			},
+			want: []string{"metadata.labels.app", "spec.params", "spec.steps[0].image", "spec.steps[0].timeout"},
+		},
+		{
+			name: "list of a different length",
+			live: map[string]interface{}{
+				"apiVersion": "tekton.dev/v1",
+				"kind":       "Task",
+				"metadata":   map[string]interface{}{"name": "preflight", "labels": map[string]interface{}{"app": "operator-pipelines"}},
+				"spec": map[string]interface{}{
+					"params": []interface{}{
+						map[string]interface{}{"name": "bundle_path", "default": "."},
+					},
+					"steps": []interface{}{
+						map[string]interface{}{"name": "preflight", "image": "quay.io/opdev/preflight:stable", "timeout": int64(300)},
+						map[string]interface{}{"name": "injected", "image": "quay.io/example/injected"},
+					},
+				},
+			},
+			want: []string{"spec.steps"},
+		},
+		{
+			name: "missing labels",
+			live: map[string]interface{}{
+				"apiVersion": "tekton.dev/v1",
+				"kind":       "Task",
+				"metadata":   map[string]interface{}{"name": "preflight"},
+				"spec":       desired["spec"],
+			},
+			want: []string{"metadata.labels"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffFields(desired, tt.live)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffFields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
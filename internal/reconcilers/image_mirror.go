@@ -0,0 +1,140 @@
+package reconcilers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// mirrorImages rewrites the image references of a Task or Pipeline with the image mirrors of the pipeline. Param
+// defaults are only reported as unmapped when the param name mentions an image, since they hold other values too.
+func (r *PipelineDependenciesReconciler) mirrorImages(pipeline *v1alpha1.OperatorPipeline, obj *unstructured.Unstructured) {
+	if len(pipeline.Spec.ImageMirrors) == 0 {
+		return
+	}
+
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	switch obj.GetKind() {
+	case "Task":
+		r.mirrorTaskSpec(pipeline, spec)
+	case "Pipeline":
+		for _, field := range []string{"tasks", "finally"} {
+			tasks, _ := spec[field].([]interface{})
+			for _, task := range tasks {
+				taskSpec, ok := nestedMap(task, "taskSpec")
+				if ok {
+					r.mirrorTaskSpec(pipeline, taskSpec)
+				}
+			}
+		}
+
+		params, _ := spec["params"].([]interface{})
+		for _, param := range params {
+			p, ok := param.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := p["name"].(string)
+			r.mirrorImageField(pipeline, p, "default", strings.Contains(strings.ToLower(name), "image"))
+		}
+	}
+}
+
+// mirrorTaskSpec rewrites the images of the steps, step template and sidecars of a Task spec.
+func (r *PipelineDependenciesReconciler) mirrorTaskSpec(pipeline *v1alpha1.OperatorPipeline, spec map[string]interface{}) {
+	for _, field := range []string{"steps", "sidecars"} {
+		containers, _ := spec[field].([]interface{})
+		for _, container := range containers {
+			if c, ok := container.(map[string]interface{}); ok {
+				r.mirrorImageField(pipeline, c, "image", true)
+			}
+		}
+	}
+
+	if stepTemplate, ok := spec["stepTemplate"].(map[string]interface{}); ok {
+		r.mirrorImageField(pipeline, stepTemplate, "image", true)
+	}
+}
+
+// mirrorImageField rewrites the image reference in the field of the object. References set entirely by a param,
+// such as $(params.image), are resolved by Tekton at run time and left alone.
+func (r *PipelineDependenciesReconciler) mirrorImageField(pipeline *v1alpha1.OperatorPipeline, obj map[string]interface{}, field string, reportUnmapped bool) {
+	image, ok := obj[field].(string)
+	if !ok || len(image) == 0 || strings.HasPrefix(image, "$(") {
+		return
+	}
+
+	mirrored, ok := mirrorImage(pipeline.Spec.ImageMirrors, image)
+	if !ok {
+		if reportUnmapped {
+			if r.unmappedImages == nil {
+				r.unmappedImages = map[string]bool{}
+			}
+			r.unmappedImages[image] = true
+		}
+		return
+	}
+
+	if r.rewrittenImages == nil {
+		r.rewrittenImages = map[string]string{}
+	}
+	r.rewrittenImages[image] = mirrored
+	obj[field] = mirrored
+}
+
+// mirrorImage rewrites the image reference with the mapping whose source is the longest prefix of the reference.
+// The source must be followed by a path, tag or digest separator, or be the whole reference.
+func mirrorImage(mirrors []v1alpha1.ImageMirror, image string) (string, bool) {
+	var match *v1alpha1.ImageMirror
+	for i, mirror := range mirrors {
+		source := strings.TrimSuffix(mirror.Source, "/")
+		if !strings.HasPrefix(image, source) {
+			continue
+		}
+		if len(image) > len(source) && !strings.ContainsRune("/:@", rune(image[len(source)])) {
+			continue
+		}
+		if match == nil || len(source) > len(strings.TrimSuffix(match.Source, "/")) {
+			match = &mirrors[i]
+		}
+	}
+
+	if match == nil {
+		return "", false
+	}
+	source := strings.TrimSuffix(match.Source, "/")
+	return strings.TrimSuffix(match.Mirror, "/") + image[len(source):], true
+}
+
+// imageStatus returns the rewritten and unmapped image references, sorted for a stable status.
+func (r *PipelineDependenciesReconciler) imageStatus() ([]v1alpha1.RewrittenImage, []string) {
+	var rewritten []v1alpha1.RewrittenImage
+	for original, mirrored := range r.rewrittenImages {
+		rewritten = append(rewritten, v1alpha1.RewrittenImage{Original: original, Mirrored: mirrored})
+	}
+	sort.Slice(rewritten, func(i, j int) bool { return rewritten[i].Original < rewritten[j].Original })
+
+	var unmapped []string
+	for image := range r.unmappedImages {
+		unmapped = append(unmapped, image)
+	}
+	sort.Strings(unmapped)
+
+	return rewritten, unmapped
+}
+
+func nestedMap(obj interface{}, field string) (map[string]interface{}, bool) {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	nested, ok := m[field].(map[string]interface{})
+	return nested, ok
+}
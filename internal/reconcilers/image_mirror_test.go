@@ -0,0 +1,136 @@
+package reconcilers
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestMirrorImage(t *testing.T) {
+	mirrors := []v1alpha1.ImageMirror{
+		{Source: "quay.io", Mirror: "mirror.example.com/quay"},
+		{Source: "quay.io/opdev/", Mirror: "mirror.example.com/opdev/"},
+		{Source: "registry.redhat.io/ubi9/ubi", Mirror: "mirror.example.com/ubi"},
+	}
+
+	tests := []struct {
+		image  string
+		want   string
+		wantOK bool
+	}{
+		{"quay.io/redhat-isv/operator-pipelines-images:latest", "mirror.example.com/quay/redhat-isv/operator-pipelines-images:latest", true},
+		// The longest source wins, with or without a trailing slash
+		{"quay.io/opdev/preflight:stable", "mirror.example.com/opdev/preflight:stable", true},
+		{"registry.redhat.io/ubi9/ubi:9.4", "mirror.example.com/ubi:9.4", true},
+		{"registry.redhat.io/ubi9/ubi@sha256:0123", "mirror.example.com/ubi@sha256:0123", true},
+		{"registry.redhat.io/ubi9/ubi", "mirror.example.com/ubi", true},
+		// The source must end at a separator
+		{"registry.redhat.io/ubi9/ubi-minimal:9.4", "", false},
+		{"quay.io.example.com/image:latest", "", false},
+		{"docker.io/library/busybox", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := mirrorImage(mirrors, tt.image)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("mirrorImage(%q) = %q, %v, want %q, %v", tt.image, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPipelineDependenciesReconcilerMirrorImages(t *testing.T) {
+	manifest := `apiVersion: tekton.dev/v1
+kind: Pipeline
+metadata:
+  name: operator-ci-pipeline
+spec:
+  params:
+  - name: preflight_image
+    default: quay.io/opdev/preflight:stable
+  - name: registry
+    default: quay.io
+  - name: base_image
+    default: docker.io/library/busybox
+  tasks:
+  - name: build
+    taskSpec:
+      stepTemplate:
+        image: quay.io/opdev/base
+      steps:
+      - name: build
+        image: $(params.preflight_image)
+      - name: push
+        image: docker.io/library/buildah
+      sidecars:
+      - name: registry
+        image: quay.io/opdev/registry:2
+  finally:
+  - name: notify
+    taskRef:
+      name: notify
+`
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	pipeline := &v1alpha1.OperatorPipeline{
+		Spec: v1alpha1.OperatorPipelineSpec{
+			ImageMirrors: []v1alpha1.ImageMirror{{Source: "quay.io/opdev", Mirror: "mirror.example.com/opdev"}},
+		},
+	}
+	r := &PipelineDependenciesReconciler{}
+	r.mirrorImages(pipeline, obj)
+
+	for path, want := range map[string]string{
+		"spec.params[0].default":                    "mirror.example.com/opdev/preflight:stable",
+		"spec.params[1].default":                    "quay.io",
+		"spec.tasks[0].taskSpec.stepTemplate.image": "mirror.example.com/opdev/base",
+		"spec.tasks[0].taskSpec.steps[0].image":     "$(params.preflight_image)",
+		"spec.tasks[0].taskSpec.steps[1].image":     "docker.io/library/buildah",
+		"spec.tasks[0].taskSpec.sidecars[0].image":  "mirror.example.com/opdev/registry:2",
+	} {
+		if got := jsonPathValue(t, obj.Object, path); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+
+	rewritten, unmapped := r.imageStatus()
+	wantRewritten := []v1alpha1.RewrittenImage{
+		{Original: "quay.io/opdev/base", Mirrored: "mirror.example.com/opdev/base"},
+		{Original: "quay.io/opdev/preflight:stable", Mirrored: "mirror.example.com/opdev/preflight:stable"},
+		{Original: "quay.io/opdev/registry:2", Mirrored: "mirror.example.com/opdev/registry:2"},
+	}
+	if !reflect.DeepEqual(rewritten, wantRewritten) {
+		t.Fatalf("rewritten %+v, want %+v", rewritten, wantRewritten)
+	}
+	// Param defaults not named after an image are not reported
+	wantUnmapped := []string{"docker.io/library/buildah", "docker.io/library/busybox"}
+	if !reflect.DeepEqual(unmapped, wantUnmapped) {
+		t.Fatalf("unmapped %v, want %v", unmapped, wantUnmapped)
+	}
+}
+
+// jsonPathValue returns the string at a dotted path with list indexes, such as spec.steps[0].image.
+func jsonPathValue(t *testing.T, obj map[string]interface{}, path string) string {
+	t.Helper()
+
+	var value interface{} = obj
+	for _, field := range strings.Split(path, ".") {
+		name, index, indexed := strings.Cut(strings.TrimSuffix(field, "]"), "[")
+		value = value.(map[string]interface{})[name]
+		if indexed {
+			i, err := strconv.Atoi(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value = value.([]interface{})[i]
+		}
+	}
+	s, _ := value.(string)
+	return s
+}
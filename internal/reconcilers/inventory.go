@@ -0,0 +1,150 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func inventoryEntry(obj *unstructured.Unstructured) v1alpha1.InventoryEntry {
+	return v1alpha1.InventoryEntry{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+	}
+}
+
+// inventoryKey identifies an entry regardless of the version of its kind.
+func inventoryKey(entry v1alpha1.InventoryEntry) string {
+	gv, _ := schema.ParseGroupVersion(entry.APIVersion)
+	return fmt.Sprintf("%s/%s/%s/%s", gv.Group, entry.Kind, entry.Namespace, entry.Name)
+}
+
+func inventoryObject(entry v1alpha1.InventoryEntry) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(entry.APIVersion)
+	obj.SetKind(entry.Kind)
+	obj.SetName(entry.Name)
+	obj.SetNamespace(entry.Namespace)
+	return obj
+}
+
+// previousInventory returns the objects applied by the last reconcile. Pipelines reconciled before the inventory
+// was recorded start from their pipeline manifests, so a pipeline disabled since then is still removed.
+func (r *PipelineDependenciesReconciler) previousInventory(pipeline *v1alpha1.OperatorPipeline, manifests fs.FS) []v1alpha1.InventoryEntry {
+	if pipeline.Status.Inventory != nil {
+		return pipeline.Status.Inventory
+	}
+
+	vars, err := templateVariables(manifests, pipeline)
+	if err != nil {
+		return nil
+	}
+
+	var inventory []v1alpha1.InventoryEntry
+	for _, fileName := range []string{operatorCIPipelineYml, operatorHostedPipelineYml, operatorReleasePipelineYml} {
+		objs, err := loadManifests(manifests, path.Join(pipelineManifestsPath, fileName), r.RESTMapper(), vars, pipeline.Namespace)
+		if err != nil {
+			continue
+		}
+		for _, obj := range objs {
+			inventory = append(inventory, inventoryEntry(obj))
+		}
+	}
+	return inventory
+}
+
+// pruneInventory deletes the objects of the previous inventory that are not part of the one just applied, and
+// records the new inventory. Objects still in the inventory of another OperatorPipeline, such as the shared
+// cluster-scoped objects or tasks of a pipeline in the same namespace, are kept, and so are the objects another
+// owner took control of since they were applied. In ReportOnly mode nothing is deleted, the objects are reported
+// as unwanted drift instead.
+func (r *PipelineDependenciesReconciler) pruneInventory(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, manifests fs.FS) error {
+	log := r.Log.WithName("pruneInventory")
+
+	applied := map[string]bool{}
+	for _, entry := range r.inventory {
+		applied[inventoryKey(entry)] = true
+	}
+
+	var stale []v1alpha1.InventoryEntry
+	for _, entry := range r.previousInventory(pipeline, manifests) {
+		if !applied[inventoryKey(entry)] {
+			stale = append(stale, entry)
+		}
+	}
+
+	if len(stale) > 0 {
+		pipelines := &v1alpha1.OperatorPipelineList{}
+		if err := r.List(ctx, pipelines); err != nil {
+			return err
+		}
+
+		inUse := map[string]bool{}
+		for _, p := range pipelines.Items {
+			if p.UID == pipeline.UID {
+				continue
+			}
+			for _, entry := range p.Status.Inventory {
+				inUse[inventoryKey(entry)] = true
+			}
+		}
+
+		for _, entry := range stale {
+			if inUse[inventoryKey(entry)] {
+				continue
+			}
+			other, err := r.controlledByOther(ctx, pipeline, entry)
+			if err != nil {
+				return err
+			}
+			if other {
+				log.Info(fmt.Sprintf("not pruning %s %s, it is controlled by another owner", entry.Kind, entry.Name))
+				continue
+			}
+
+			if reportOnly(pipeline) {
+				drift, err := objectDrift(ctx, r.Client, inventoryObject(entry), v1alpha1.DriftUnwanted)
+				if err != nil {
+					return err
+				}
+				if drift != nil {
+					r.drift = append(r.drift, *drift)
+				}
+				continue
+			}
+
+			if err := r.Delete(ctx, inventoryObject(entry)); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, fmt.Sprintf("failed to prune %s %s", entry.Kind, entry.Name))
+				return err
+			}
+			log.Info(fmt.Sprintf("pruned %s %s", entry.Kind, entry.Name))
+		}
+	}
+
+	if reportOnly(pipeline) {
+		return nil
+	}
+	pipeline.Status.Inventory = r.inventory
+	return nil
+}
+
+// controlledByOther returns whether the object of the entry has a controller other than the pipeline.
+func (r *PipelineDependenciesReconciler) controlledByOther(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, entry v1alpha1.InventoryEntry) (bool, error) {
+	live := inventoryObject(entry)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(live), live); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	ref := metav1.GetControllerOf(live)
+	return ref != nil && ref.UID != pipeline.UID, nil
+}
@@ -0,0 +1,147 @@
+package reconcilers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPipelineDependenciesReconcilerPruneInventory(t *testing.T) {
+	const namespace = "operator-ci"
+	controllerRef := func(name string, uid string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "OperatorPipeline",
+			Name:       name,
+			UID:        types.UID(uid),
+			Controller: ptr.To(true),
+		}}
+	}
+	role := func(name string, owners []metav1.OwnerReference) *rbacv1.Role {
+		return &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, OwnerReferences: owners}}
+	}
+	entry := func(kind, name string) v1alpha1.InventoryEntry {
+		e := v1alpha1.InventoryEntry{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: kind, Name: name}
+		if kind == "Role" {
+			e.Namespace = namespace
+		}
+		return e
+	}
+
+	objs := []client.Object{
+		role("applied", controllerRef("operator-pipeline", "uid")),
+		role("stale", controllerRef("operator-pipeline", "uid")),
+		role("taken-over", controllerRef("other-pipeline", "other-uid")),
+		role("shared", controllerRef("operator-pipeline", "uid")),
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "stale-cluster-role"}},
+		&v1alpha1.OperatorPipeline{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-pipeline", Namespace: namespace, UID: "other-uid"},
+			Status:     v1alpha1.OperatorPipelineStatus{Inventory: []v1alpha1.InventoryEntry{entry("Role", "shared")}},
+		},
+	}
+	previous := []v1alpha1.InventoryEntry{
+		entry("Role", "applied"),
+		entry("Role", "stale"),
+		entry("Role", "taken-over"),
+		entry("Role", "shared"),
+		entry("Role", "already-deleted"),
+		entry("ClusterRole", "stale-cluster-role"),
+	}
+	applied := []v1alpha1.InventoryEntry{entry("Role", "applied")}
+
+	tests := []struct {
+		name      string
+		mode      v1alpha1.ReconcileMode
+		wantKept  []string
+		wantDrift []string
+	}{
+		{
+			name:     "stale objects pruned",
+			wantKept: []string{"Role applied", "Role shared", "Role taken-over"},
+		},
+		{
+			name:      "ReportOnly reports the stale objects",
+			mode:      v1alpha1.ReconcileModeReportOnly,
+			wantKept:  []string{"ClusterRole stale-cluster-role", "Role applied", "Role shared", "Role stale", "Role taken-over"},
+			wantDrift: []string{"Role stale", "ClusterRole stale-cluster-role"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			initial := make([]client.Object, 0, len(objs))
+			for _, obj := range objs {
+				initial = append(initial, obj.DeepCopyObject().(client.Object))
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initial...).Build()
+
+			pipeline := &v1alpha1.OperatorPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: namespace, UID: "uid"},
+				Spec:       v1alpha1.OperatorPipelineSpec{ReconcileMode: tt.mode},
+				Status:     v1alpha1.OperatorPipelineStatus{Inventory: previous},
+			}
+			r := NewPipeDependenciesReconciler(c, logr.Discard(), scheme, NewResolvedManifests(c))
+			r.inventory = applied
+			if err := r.pruneInventory(ctx, pipeline, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			var kept []string
+			for _, entry := range previous {
+				err := c.Get(ctx, client.ObjectKeyFromObject(inventoryObject(entry)), inventoryObject(entry))
+				switch {
+				case apierrors.IsNotFound(err):
+				case err != nil:
+					t.Fatal(err)
+				default:
+					kept = append(kept, entry.Kind+" "+entry.Name)
+				}
+			}
+			sort.Strings(kept)
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Fatalf("kept %v, want %v", kept, tt.wantKept)
+			}
+
+			var drift []string
+			for _, d := range r.drift {
+				if d.Type != v1alpha1.DriftUnwanted {
+					t.Fatalf("drift %+v, want %s", d, v1alpha1.DriftUnwanted)
+				}
+				drift = append(drift, d.Kind+" "+d.Name)
+			}
+			if !reflect.DeepEqual(drift, tt.wantDrift) {
+				t.Fatalf("drift %v, want %v", drift, tt.wantDrift)
+			}
+
+			// The inventory is only recorded once the stale objects are gone
+			wantInventory := previous
+			if len(tt.mode) == 0 {
+				wantInventory = applied
+			}
+			if !reflect.DeepEqual(pipeline.Status.Inventory, wantInventory) {
+				t.Fatalf("inventory %+v, want %+v", pipeline.Status.Inventory, wantInventory)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package reconcilers
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/jinja"
+
+	securityv1 "github.com/openshift/api/security/v1"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// allowedManifestKinds are the kinds the operator applies from the operator-pipelines manifests.
+// Anything else in the manifest directories is refused rather than applied with the operator's permissions.
+var allowedManifestKinds = map[schema.GroupKind]bool{
+	{Group: tekton.SchemeGroupVersion.Group, Kind: "Pipeline"}:           true,
+	{Group: tekton.SchemeGroupVersion.Group, Kind: "Task"}:               true,
+	{Group: securityv1.GroupName, Kind: "SecurityContextConstraints"}:    true,
+	{Group: rbacv1.SchemeGroupVersion.Group, Kind: "ClusterRole"}:        true,
+	{Group: rbacv1.SchemeGroupVersion.Group, Kind: "ClusterRoleBinding"}: true,
+	{Group: rbacv1.SchemeGroupVersion.Group, Kind: "Role"}:               true,
+	{Group: rbacv1.SchemeGroupVersion.Group, Kind: "RoleBinding"}:        true,
+}
+
+// templateVariables returns the variables the manifest templates are rendered with. The defaults of the
+// operator-pipeline role are overridden by the template variables of the spec, the namespace and service account
+// are always the ones of the pipeline.
+func templateVariables(manifests fs.FS, pipeline *v1alpha1.OperatorPipeline) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+
+	// Releases without role defaults only get the variables below
+	if _, err := fs.Stat(manifests, roleDefaultsPath); err == nil {
+		b, err := fs.ReadFile(manifests, roleDefaultsPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, &vars); err != nil {
+			return nil, fmt.Errorf("%s: %w", roleDefaultsPath, err)
+		}
+		// An empty defaults file decodes to nil
+		if vars == nil {
+			vars = map[string]interface{}{}
+		}
+	}
+
+	for name, value := range pipeline.Spec.TemplateVariables {
+		vars[name] = value
+	}
+	vars["oc_namespace"] = pipeline.Namespace
+	vars["service_account"] = serviceAccountName(pipeline)
+
+	return vars, nil
+}
+
+// loadManifests reads a manifest file and decodes it with decodeManifests.
+func loadManifests(manifests fs.FS, fileName string, mapper meta.RESTMapper, vars map[string]interface{}, namespace string) ([]*unstructured.Unstructured, error) {
+	b, err := fs.ReadFile(manifests, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return decodeManifests(b, fileName, mapper, vars, namespace)
+}
+
+// decodeManifests renders the manifest template with the variables and decodes every document of the resulting
+// YAML or JSON into unstructured objects. The RESTMapper tells namespaced kinds, which are placed in the given
+// namespace, apart from cluster-scoped ones, which are left without a namespace.
+func decodeManifests(b []byte, fileName string, mapper meta.RESTMapper, vars map[string]interface{}, namespace string) ([]*unstructured.Unstructured, error) {
+	rendered, err := jinja.Render(string(b), vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	var objs []*unstructured.Unstructured
+	decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(rendered), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+
+		// Empty documents, like the one after a trailing ---, decode to nothing
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		if len(gvk.Kind) == 0 || len(gvk.Version) == 0 || len(obj.GetName()) == 0 {
+			return nil, fmt.Errorf("%s: every document needs an apiVersion, a kind and a name", fileName)
+		}
+
+		if !allowedManifestKinds[gvk.GroupKind()] {
+			return nil, fmt.Errorf("%w: %s %s in %s", errors.ErrManifestKindNotAllowed, gvk.Kind, obj.GetName(), fileName)
+		}
+
+		// Older releases ship v1beta1 Tekton objects, they are applied as v1
+		obj, err = convertTektonManifest(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+		gvk = obj.GroupVersionKind()
+
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj.SetNamespace(namespace)
+		} else {
+			obj.SetNamespace("")
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
@@ -0,0 +1,116 @@
+package reconcilers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	operrors "github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
+
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(tekton.SchemeGroupVersion.WithKind("Task"), meta.RESTScopeNamespace)
+	mapper.Add(tekton.SchemeGroupVersion.WithKind("Pipeline"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	vars := map[string]interface{}{"oc_namespace": "operator-ci", "service_account": "pipeline"}
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+		wantErr  string
+		wantIs   error
+	}{
+		{
+			name: "multi-document YAML",
+			manifest: `---
+apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: preflight
+  namespace: default
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: pipelines-scc-clusterrole
+  namespace: "{{ oc_namespace }}"
+---
+`,
+			want: []string{"Task operator-ci/preflight", "ClusterRole /pipelines-scc-clusterrole"},
+		},
+		{
+			name:     "rendered JSON",
+			manifest: `{"apiVersion": "tekton.dev/v1", "kind": "Pipeline", "metadata": {"name": "{{ service_account }}-pipeline"}}`,
+			want:     []string{"Pipeline operator-ci/pipeline-pipeline"},
+		},
+		{
+			name:     "only empty documents",
+			manifest: "---\n---\n",
+		},
+		{
+			name: "kind outside the allow-list",
+			manifest: `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: preflight
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: injected
+`,
+			wantErr: "ConfigMap injected in tasks.yml",
+			wantIs:  operrors.ErrManifestKindNotAllowed,
+		},
+		{
+			name: "document without a name",
+			manifest: `apiVersion: tekton.dev/v1
+kind: Task
+metadata: {}
+`,
+			wantErr: "every document needs an apiVersion, a kind and a name",
+		},
+		{
+			name: "allowed kind unknown to the cluster",
+			manifest: `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: pipeline
+`,
+			wantErr: "no matches for kind",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := decodeManifests([]byte(tt.manifest), "tasks.yml", mapper, vars, "operator-ci")
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Fatalf("error %v, want %v", err, tt.wantIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+" "+obj.GetNamespace()+"/"+obj.GetName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("objects %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+const patchesAppliedCondition = "PatchesApplied"
+
+// patchObject applies, in order, the patches of the pipeline targeting the object. When a patch fails the failure
+// is recorded for the status and false is returned, the object must then be left as it is in the cluster.
+func (r *PipelineDependenciesReconciler) patchObject(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, obj *unstructured.Unstructured) (*unstructured.Unstructured, bool) {
+	patched := obj
+	for _, patch := range pipeline.Spec.Patches {
+		if patch.Target.Kind != obj.GetKind() || (len(patch.Target.Name) > 0 && patch.Target.Name != obj.GetName()) {
+			continue
+		}
+
+		result, err := r.applyPatch(ctx, pipeline, patch, patched)
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("failed to patch %s %s with %s %s", obj.GetKind(), obj.GetName(), patch.ConfigMapName, patch.Key))
+			r.patchFailures = append(r.patchFailures, v1alpha1.PatchFailure{
+				Kind:          obj.GetKind(),
+				Name:          obj.GetName(),
+				ConfigMapName: patch.ConfigMapName,
+				Key:           patch.Key,
+				Message:       err.Error(),
+			})
+			return nil, false
+		}
+		patched = result
+	}
+
+	return patched, true
+}
+
+func (r *PipelineDependenciesReconciler) applyPatch(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, patch v1alpha1.ManifestPatch, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: patch.ConfigMapName}, cm); err != nil {
+		return nil, err
+	}
+
+	data, ok := cm.Data[patch.Key]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s does not contain the key %s", patch.ConfigMapName, patch.Key)
+	}
+
+	patchJSON, err := yaml.YAMLToJSON([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	switch patch.Type {
+	case v1alpha1.PatchTypeJSON6902:
+		decoded, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return nil, err
+		}
+		if result, err = decoded.Apply(original); err != nil {
+			return nil, err
+		}
+	case v1alpha1.PatchTypeStrategicMerge:
+		// The typed object provides the merge keys, lists of kinds without any are replaced as a whole
+		dataStruct, err := r.Scheme.New(obj.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		if result, err = strategicpatch.StrategicMergePatch(original, patchJSON, dataStruct); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported patch type %s", patch.Type)
+	}
+
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(result); err != nil {
+		return nil, err
+	}
+
+	if patched.GroupVersionKind() != obj.GroupVersionKind() || patched.GetName() != obj.GetName() ||
+		patched.GetNamespace() != obj.GetNamespace() {
+		return nil, fmt.Errorf("patches must not change the kind, name or namespace of the object")
+	}
+
+	return patched, nil
+}
@@ -0,0 +1,168 @@
+package reconcilers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPipelineDependenciesReconcilerPatchObject(t *testing.T) {
+	patches := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "pipeline-patches", Namespace: "operator-ci"},
+		Data: map[string]string{
+			"preflight-image.yaml": `- op: replace
+  path: /spec/steps/0/image
+  value: quay.io/example/preflight:1.0.0
+`,
+			"labels.json": `[{"op": "add", "path": "/metadata/labels/team", "value": "isv"}]`,
+			"missing-path.yaml": `- op: replace
+  path: /spec/sidecars/0/image
+  value: quay.io/example/sidecar
+`,
+			"rename.yaml": `- op: replace
+  path: /metadata/name
+  value: renamed
+`,
+			"strategic.yaml": `metadata:
+  labels:
+    team: isv
+spec:
+  params:
+  - name: bundle_path
+    default: operators
+`,
+		},
+	}
+	task := func(labels map[string]interface{}, image string, params ...interface{}) map[string]interface{} {
+		spec := map[string]interface{}{
+			"steps": []interface{}{
+				map[string]interface{}{"name": "preflight", "image": image},
+			},
+		}
+		if len(params) > 0 {
+			spec["params"] = params
+		}
+		return map[string]interface{}{
+			"apiVersion": "tekton.dev/v1",
+			"kind":       "Task",
+			"metadata":   map[string]interface{}{"name": "preflight", "namespace": "operator-ci", "labels": labels},
+			"spec":       spec,
+		}
+	}
+	original := task(map[string]interface{}{"app": "operator-pipelines"}, "quay.io/opdev/preflight:stable")
+	patch := func(patchType v1alpha1.PatchType, kind, name, key string) v1alpha1.ManifestPatch {
+		return v1alpha1.ManifestPatch{
+			Target:        v1alpha1.PatchTarget{Kind: kind, Name: name},
+			Type:          patchType,
+			ConfigMapName: patches.Name,
+			Key:           key,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		patches     []v1alpha1.ManifestPatch
+		want        map[string]interface{}
+		wantFailure string
+	}{
+		{
+			name:    "JSON 6902 patch in YAML",
+			patches: []v1alpha1.ManifestPatch{patch(v1alpha1.PatchTypeJSON6902, "Task", "preflight", "preflight-image.yaml")},
+			want:    task(map[string]interface{}{"app": "operator-pipelines"}, "quay.io/example/preflight:1.0.0"),
+		},
+		{
+			name:    "strategic merge patch",
+			patches: []v1alpha1.ManifestPatch{patch(v1alpha1.PatchTypeStrategicMerge, "Task", "", "strategic.yaml")},
+			want: task(map[string]interface{}{"app": "operator-pipelines", "team": "isv"}, "quay.io/opdev/preflight:stable",
+				map[string]interface{}{"name": "bundle_path", "default": "operators"}),
+		},
+		{
+			name: "patches applied in order",
+			patches: []v1alpha1.ManifestPatch{
+				patch(v1alpha1.PatchTypeJSON6902, "Task", "", "preflight-image.yaml"),
+				patch(v1alpha1.PatchTypeJSON6902, "Task", "preflight", "labels.json"),
+			},
+			want: task(map[string]interface{}{"app": "operator-pipelines", "team": "isv"}, "quay.io/example/preflight:1.0.0"),
+		},
+		{
+			name: "patches of other objects",
+			patches: []v1alpha1.ManifestPatch{
+				patch(v1alpha1.PatchTypeJSON6902, "Pipeline", "", "labels.json"),
+				patch(v1alpha1.PatchTypeJSON6902, "Task", "operator-validation", "labels.json"),
+			},
+			want: original,
+		},
+		{
+			name:        "missing key",
+			patches:     []v1alpha1.ManifestPatch{patch(v1alpha1.PatchTypeJSON6902, "Task", "", "missing.yaml")},
+			wantFailure: "ConfigMap pipeline-patches does not contain the key missing.yaml",
+		},
+		{
+			name:        "path not in the object",
+			patches:     []v1alpha1.ManifestPatch{patch(v1alpha1.PatchTypeJSON6902, "Task", "", "missing-path.yaml")},
+			wantFailure: "/spec/sidecars/0/image",
+		},
+		{
+			name:        "object renamed",
+			patches:     []v1alpha1.ManifestPatch{patch(v1alpha1.PatchTypeJSON6902, "Task", "", "rename.yaml")},
+			wantFailure: "patches must not change the kind, name or namespace of the object",
+		},
+		{
+			name: "failure after a successful patch",
+			patches: []v1alpha1.ManifestPatch{
+				patch(v1alpha1.PatchTypeJSON6902, "Task", "", "labels.json"),
+				patch(v1alpha1.PatchTypeJSON6902, "Task", "", "rename.yaml"),
+			},
+			wantFailure: "patches must not change the kind, name or namespace of the object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := tekton.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(patches.DeepCopy()).Build()
+
+			pipeline := &v1alpha1.OperatorPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci"},
+				Spec:       v1alpha1.OperatorPipelineSpec{Patches: tt.patches},
+			}
+			obj := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(original)}
+			r := NewPipeDependenciesReconciler(c, logr.Discard(), scheme, NewResolvedManifests(c))
+			patched, ok := r.patchObject(context.Background(), pipeline, obj)
+
+			if len(tt.wantFailure) > 0 {
+				if ok || len(r.patchFailures) != 1 || !strings.Contains(r.patchFailures[0].Message, tt.wantFailure) {
+					t.Fatalf("patched %v with failures %+v, want a failure mentioning %q", ok, r.patchFailures, tt.wantFailure)
+				}
+				return
+			}
+			if !ok || len(r.patchFailures) > 0 {
+				t.Fatalf("patch failures %+v", r.patchFailures)
+			}
+			if !reflect.DeepEqual(patched.Object, tt.want) {
+				t.Fatalf("patched object %v, want %v", patched.Object, tt.want)
+			}
+			// The manifest itself is left as it is
+			if !reflect.DeepEqual(obj.Object, original) {
+				t.Fatalf("manifest changed to %v", obj.Object)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package reconcilers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultManifestArchiveKey = "manifests.tar.gz"
+
+// ManifestSource provides the operator-pipelines manifests a pipeline is installed from.
+type ManifestSource interface {
+	// Manifests returns the operator-pipelines tree for the pipeline. Paths in the returned
+	// file system are relative to the root of the operator-pipelines repository. Archive sources record the
+	// digest of the archive in the status of the pipeline, so unused extractions can be pruned.
+	Manifests(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (fs.FS, error)
+
+	// Describe returns a human readable description of where the manifests come from.
+	Describe(pipeline *v1alpha1.OperatorPipeline) string
+}
+
+// NewManifestSource returns the manifest source selected by the pipeline spec.
+func NewManifestSource(c client.Client, pipeline *v1alpha1.OperatorPipeline) ManifestSource {
+	source := pipeline.Spec.ManifestSource
+	if source == nil {
+		return &gitManifestSource{}
+	}
+
+	switch source.Type {
+	case v1alpha1.ManifestSourceArchive:
+		return &archiveManifestSource{path: source.Path}
+	case v1alpha1.ManifestSourceConfigMap, v1alpha1.ManifestSourceSecret:
+		return &objectManifestSource{Client: c, sourceType: source.Type, name: source.Name, key: source.Key}
+	default:
+		return &gitManifestSource{}
+	}
+}
+
+// ResolvedManifests resolves the manifest source selected by the pipeline spec once per reconcile. The reconcilers
+// reading the manifests share the tree, which is then only extracted or read from the checkout once, and they all
+// see the same manifests. It is resolved on first use, after the git reconciler has resolved the commit.
+type ResolvedManifests struct {
+	client   client.Client
+	source   ManifestSource
+	fsys     fs.FS
+	err      error
+	resolved bool
+}
+
+// NewResolvedManifests returns the manifests of a single reconcile of a pipeline.
+func NewResolvedManifests(c client.Client) *ResolvedManifests {
+	return &ResolvedManifests{client: c}
+}
+
+func (m *ResolvedManifests) Manifests(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (fs.FS, error) {
+	if !m.resolved {
+		m.fsys, m.err = m.selected(pipeline).Manifests(ctx, pipeline)
+		m.resolved = true
+	}
+	return m.fsys, m.err
+}
+
+func (m *ResolvedManifests) Describe(pipeline *v1alpha1.OperatorPipeline) string {
+	return m.selected(pipeline).Describe(pipeline)
+}
+
+func (m *ResolvedManifests) selected(pipeline *v1alpha1.OperatorPipeline) ManifestSource {
+	if m.source == nil {
+		m.source = NewManifestSource(m.client, pipeline)
+	}
+	return m.source
+}
+
+// usesGitManifestSource returns whether the manifests are read from the operator-pipelines git repository.
+func usesGitManifestSource(pipeline *v1alpha1.OperatorPipeline) bool {
+	source := pipeline.Spec.ManifestSource
+	return source == nil || source.Type == v1alpha1.ManifestSourceGit || len(source.Type) == 0
+}
+
+// gitManifestSource reads the manifests from the checkout of the commit resolved by the git reconciler.
+type gitManifestSource struct{}
+
+func (s *gitManifestSource) Manifests(_ context.Context, pipeline *v1alpha1.OperatorPipeline) (fs.FS, error) {
+	checkoutPath, err := pipelineCheckout(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.Status.ManifestArchiveDigest = ""
+	return os.DirFS(checkoutPath), nil
+}
+
+func (s *gitManifestSource) Describe(pipeline *v1alpha1.OperatorPipeline) string {
+	return fmt.Sprintf("git repo %s at %s", pipelinesRepoURL(pipeline), pipeline.Status.PipelinesRepoHash)
+}
+
+// archiveManifestSource reads the manifests from a tar archive mounted into the operator pod,
+// for clusters that cannot reach the operator-pipelines git repository.
+type archiveManifestSource struct {
+	path string
+}
+
+func (s *archiveManifestSource) Manifests(_ context.Context, pipeline *v1alpha1.OperatorPipeline) (fs.FS, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.Status.ManifestArchiveDigest = archiveDigest(b)
+	return extractManifestArchive(b)
+}
+
+func (s *archiveManifestSource) Describe(_ *v1alpha1.OperatorPipeline) string {
+	return fmt.Sprintf("archive %s", s.path)
+}
+
+// objectManifestSource reads the manifests from a tar archive stored in a ConfigMap or Secret
+// in the namespace of the pipeline.
+type objectManifestSource struct {
+	client.Client
+	sourceType v1alpha1.ManifestSourceType
+	name       string
+	key        string
+}
+
+func (s *objectManifestSource) Manifests(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (fs.FS, error) {
+	key := s.archiveKey()
+	nsName := types.NamespacedName{Namespace: pipeline.Namespace, Name: s.name}
+
+	var b []byte
+	var ok bool
+	if s.sourceType == v1alpha1.ManifestSourceSecret {
+		secret := &corev1.Secret{}
+		if err := s.Get(ctx, nsName, secret); err != nil {
+			return nil, err
+		}
+		b, ok = secret.Data[key]
+	} else {
+		cm := &corev1.ConfigMap{}
+		if err := s.Get(ctx, nsName, cm); err != nil {
+			return nil, err
+		}
+		if b, ok = cm.BinaryData[key]; !ok {
+			var data string
+			data, ok = cm.Data[key]
+			b = []byte(data)
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%s %s does not contain the key %s", s.sourceType, s.name, key)
+	}
+
+	pipeline.Status.ManifestArchiveDigest = archiveDigest(b)
+	return extractManifestArchive(b)
+}
+
+func (s *objectManifestSource) Describe(_ *v1alpha1.OperatorPipeline) string {
+	return fmt.Sprintf("%s %s key %s", s.sourceType, s.name, s.archiveKey())
+}
+
+func (s *objectManifestSource) archiveKey() string {
+	if len(s.key) > 0 {
+		return s.key
+	}
+	return defaultManifestArchiveKey
+}
+
+// extractManifestArchive extracts a tar archive, optionally gzip compressed, to a directory named after the
+// archive digest so every version of an archive is only extracted once. Archives containing a single top level
+// directory, like the ones produced by GitHub for a release, are rooted at that directory.
+func extractManifestArchive(archive []byte) (fs.FS, error) {
+	archivesPath, err := manifestArchivesPath()
+	if err != nil {
+		return nil, err
+	}
+	targetPath := filepath.Join(archivesPath, archiveDigest(archive))
+
+	if _, err := os.Stat(targetPath); err != nil {
+		if err := extractTar(archive, targetPath); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return os.DirFS(filepath.Join(targetPath, entries[0].Name())), nil
+	}
+
+	return os.DirFS(targetPath), nil
+}
+
+// archiveDigest returns the hex encoded sha256 digest of the archive, which names its extraction.
+func archiveDigest(archive []byte) string {
+	sum := sha256.Sum256(archive)
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestArchivesPath returns the directory the manifest archives are extracted to.
+func manifestArchivesPath() (string, error) {
+	gitMount, ok := os.LookupEnv("GIT_REPO_PATH")
+	if !ok {
+		return "", errors.ErrGitRepoPathNotSpecified
+	}
+	return filepath.Join(gitMount, "archives"), nil
+}
+
+// pruneManifestArchives removes the archive extractions that no OperatorPipeline in the cluster is using anymore.
+// Extractions still in progress are left alone.
+func pruneManifestArchives(ctx context.Context, c client.Client, pipeline *v1alpha1.OperatorPipeline) error {
+	pipelines := &v1alpha1.OperatorPipelineList{}
+	if err := c.List(ctx, pipelines); err != nil {
+		return err
+	}
+
+	inUse := map[string]bool{pipeline.Status.ManifestArchiveDigest: true}
+	for _, p := range pipelines.Items {
+		inUse[p.Status.ManifestArchiveDigest] = true
+	}
+
+	archivesPath, err := manifestArchivesPath()
+	if err != nil {
+		return err
+	}
+
+	return pruneUnused(archivesPath, inUse)
+}
+
+// extractTar writes the regular files of the archive to a temporary directory that is renamed to targetPath once
+// complete, so a partially extracted archive is never used.
+func extractTar(archive []byte, targetPath string) error {
+	var reader io.Reader = bytes.NewReader(archive)
+	if bytes.HasPrefix(archive, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+		return err
+	}
+
+	tmpPath, err := os.MkdirTemp(filepath.Dir(targetPath), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Only regular files are needed, links are skipped so nothing can point outside of the archive
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if !fs.ValidPath(name) {
+			return fmt.Errorf("invalid path %s in manifest archive", header.Name)
+		}
+
+		filePath := filepath.Join(tmpPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil { //nolint:gosec // archives are provided by the cluster admin
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		if _, statErr := os.Stat(targetPath); statErr == nil {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
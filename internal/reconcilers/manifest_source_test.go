@@ -0,0 +1,228 @@
+package reconcilers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestExtractManifestArchive(t *testing.T) {
+	type entry struct {
+		name     string
+		typeflag byte
+		contents string
+		linkname string
+	}
+	archive := func(compress bool, entries ...entry) []byte {
+		var buf bytes.Buffer
+		var gz *gzip.Writer
+		tw := tar.NewWriter(&buf)
+		if compress {
+			gz = gzip.NewWriter(&buf)
+			tw = tar.NewWriter(gz)
+		}
+		for _, e := range entries {
+			header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644, Size: int64(len(e.contents))}
+			if e.typeflag != tar.TypeReg {
+				header.Size = 0
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			if e.typeflag == tar.TypeReg {
+				if _, err := tw.Write([]byte(e.contents)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if gz != nil {
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buf.Bytes()
+	}
+	task := "ansible/roles/operator-pipeline/templates/openshift/tasks/preflight.yml"
+
+	tests := []struct {
+		name      string
+		archive   []byte
+		wantFiles map[string]string
+		wantErr   string
+	}{
+		{
+			name: "gzip archive rooted at its top level directory",
+			archive: archive(true,
+				entry{name: "operator-pipelines-1.0.0/", typeflag: tar.TypeDir},
+				entry{name: "operator-pipelines-1.0.0/" + task, typeflag: tar.TypeReg, contents: "kind: Task\n"},
+			),
+			wantFiles: map[string]string{task: "kind: Task\n"},
+		},
+		{
+			name: "tar archive with several top level entries",
+			archive: archive(false,
+				entry{name: task, typeflag: tar.TypeReg, contents: "kind: Task\n"},
+				entry{name: "README.md", typeflag: tar.TypeReg, contents: "# operator-pipelines\n"},
+			),
+			wantFiles: map[string]string{task: "kind: Task\n", "README.md": "# operator-pipelines\n"},
+		},
+		{
+			name: "links are skipped",
+			archive: archive(false,
+				entry{name: task, typeflag: tar.TypeReg, contents: "kind: Task\n"},
+				entry{name: "README.md", typeflag: tar.TypeReg, contents: "# operator-pipelines\n"},
+				entry{name: "passwd", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+				entry{name: "hardlink.yml", typeflag: tar.TypeLink, linkname: task},
+			),
+			wantFiles: map[string]string{task: "kind: Task\n", "README.md": "# operator-pipelines\n"},
+		},
+		{
+			name: "absolute paths are extracted in the archive",
+			archive: archive(false,
+				entry{name: "/" + task, typeflag: tar.TypeReg, contents: "kind: Task\n"},
+				entry{name: "/README.md", typeflag: tar.TypeReg, contents: "# operator-pipelines\n"},
+			),
+			wantFiles: map[string]string{task: "kind: Task\n", "README.md": "# operator-pipelines\n"},
+		},
+		{
+			name: "paths leaving the archive are rejected",
+			archive: archive(false,
+				entry{name: task, typeflag: tar.TypeReg, contents: "kind: Task\n"},
+				entry{name: "../../escaped.yml", typeflag: tar.TypeReg, contents: "kind: Task\n"},
+			),
+			wantErr: "invalid path ../../escaped.yml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitPath := t.TempDir()
+			t.Setenv("GIT_REPO_PATH", gitPath)
+
+			manifests, err := extractManifestArchive(tt.archive)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				// Nothing is left behind, not even the partial extraction
+				entries, err := os.ReadDir(filepath.Join(gitPath, "archives"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) > 0 {
+					t.Fatalf("extraction left %v behind", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files := map[string]string{}
+			err = fs.WalkDir(manifests, ".", func(name string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				b, err := fs.ReadFile(manifests, name)
+				files[name] = string(b)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Fatalf("files %v, want %v", files, tt.wantFiles)
+			}
+
+			// Every version of an archive is extracted once, to a directory named after its digest
+			entries, err := os.ReadDir(filepath.Join(gitPath, "archives"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			sort.Strings(names)
+			if want := []string{archiveDigest(tt.archive)}; !reflect.DeepEqual(names, want) {
+				t.Fatalf("extractions %v, want %v", names, want)
+			}
+		})
+	}
+}
+
+func TestResolvedManifests(t *testing.T) {
+	t.Setenv("GIT_REPO_PATH", t.TempDir())
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	contents := "kind: Task\n"
+	if err := tw.WriteHeader(&tar.Header{Name: "task.yml", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(contents))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipelines", Namespace: "operator-ci"},
+		BinaryData: map[string][]byte{defaultManifestArchiveKey: buf.Bytes()},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+
+	pipeline := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci"},
+		Spec: v1alpha1.OperatorPipelineSpec{
+			ManifestSource: &v1alpha1.ManifestSource{Type: v1alpha1.ManifestSourceConfigMap, Name: cm.Name},
+		},
+	}
+	ctx := context.Background()
+	manifests := NewResolvedManifests(c)
+	first, err := manifests.Manifests(ctx, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipeline.Status.ManifestArchiveDigest != archiveDigest(buf.Bytes()) {
+		t.Fatalf("archive digest %q, want %q", pipeline.Status.ManifestArchiveDigest, archiveDigest(buf.Bytes()))
+	}
+
+	// The ConfigMap is only read once per reconcile
+	if err := c.Delete(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	second, err := manifests.Manifests(ctx, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Fatal("manifests resolved again")
+	}
+	if description := manifests.Describe(pipeline); description != "ConfigMap operator-pipelines key manifests.tar.gz" {
+		t.Fatalf("description %q", description)
+	}
+}
@@ -0,0 +1,116 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/pkg/apis"
+)
+
+const manifestsValidCondition = "ManifestsValid"
+
+// manifestObject is an object of the pipeline manifests along with the file it was loaded from.
+type manifestObject struct {
+	fileName string
+	obj      *unstructured.Unstructured
+	// patchFailed objects could not be patched, they are left as they are in the cluster
+	patchFailed bool
+	// unselected Tasks are part of the release but not needed by the pipeline, they are not applied
+	unselected bool
+}
+
+// validateManifests runs the Tekton objects through Tekton's own defaulting and validation, as its admission
+// webhook would, and checks that every taskRef of the Pipelines resolves to a Task of the release that is
+// installed. It returns the reasons the objects are invalid, prefixed with their file.
+func validateManifests(ctx context.Context, objs []manifestObject) []string {
+	// Unselected Tasks are not installed, a taskRef to one of them was excluded by spec.tasks
+	tasks := map[string]bool{}
+	excluded := map[string]bool{}
+	for _, o := range objs {
+		if !isTask(o.obj) {
+			continue
+		}
+		if o.unselected {
+			excluded[o.obj.GetName()] = true
+		} else {
+			tasks[o.obj.GetName()] = true
+		}
+	}
+
+	var invalid []string
+	for _, o := range objs {
+		// Objects whose patches failed are not applied, their live state is what counts, and unselected Tasks are
+		// not applied at all
+		if o.patchFailed || o.unselected {
+			continue
+		}
+
+		switch o.obj.GroupVersionKind() {
+		case tekton.SchemeGroupVersion.WithKind("Task"):
+			task := &tekton.Task{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(o.obj.Object, task); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s: Task %s: %v", o.fileName, o.obj.GetName(), err))
+				continue
+			}
+			task.SetDefaults(ctx)
+			if err := task.Validate(ctx); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s: Task %s: %s", o.fileName, o.obj.GetName(), fieldErrorMessage(err)))
+			}
+		case tekton.SchemeGroupVersion.WithKind("Pipeline"):
+			pipeline := &tekton.Pipeline{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(o.obj.Object, pipeline); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s: Pipeline %s: %v", o.fileName, o.obj.GetName(), err))
+				continue
+			}
+			pipeline.SetDefaults(ctx)
+			if err := pipeline.Validate(ctx); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s: Pipeline %s: %s", o.fileName, o.obj.GetName(), fieldErrorMessage(err)))
+			}
+			for _, ref := range unresolvedTaskRefs(pipeline, tasks) {
+				reason := "is not a Task of the release"
+				if excluded[ref] {
+					reason = "is excluded by spec.tasks"
+				}
+				invalid = append(invalid, fmt.Sprintf("%s: Pipeline %s: taskRef %s %s", o.fileName, o.obj.GetName(), ref, reason))
+			}
+		}
+	}
+
+	return invalid
+}
+
+// fieldErrorMessage puts the errors Tekton reports on separate lines on a single one, for the condition message.
+func fieldErrorMessage(err *apis.FieldError) string {
+	return strings.ReplaceAll(err.Error(), "\n", ", ")
+}
+
+// unresolvedTaskRefs returns the Tasks referenced by the pipeline tasks that are not among the given ones.
+func unresolvedTaskRefs(pipeline *tekton.Pipeline, tasks map[string]bool) []string {
+	var unresolved []string
+	for _, name := range pipelineTaskRefs(pipeline) {
+		if !tasks[name] {
+			unresolved = append(unresolved, name)
+		}
+	}
+	return unresolved
+}
+
+// pipelineTaskRefs returns the names of the Tasks referenced by the pipeline tasks. References to remote Tasks,
+// through a resolver, or to other kinds are left out.
+func pipelineTaskRefs(pipeline *tekton.Pipeline) []string {
+	var names []string
+	for _, pipelineTasks := range [][]tekton.PipelineTask{pipeline.Spec.Tasks, pipeline.Spec.Finally} {
+		for _, pt := range pipelineTasks {
+			ref := pt.TaskRef
+			if ref == nil || len(ref.Resolver) > 0 || (len(ref.Kind) > 0 && ref.Kind != tekton.NamespacedTaskKind) {
+				continue
+			}
+			names = append(names, ref.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,82 @@
+package reconcilers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testTask(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tekton.dev/v1",
+		"kind":       "Task",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"steps": []interface{}{map[string]interface{}{"name": "run", "image": "busybox", "script": "true"}},
+		},
+	}}
+}
+
+func testPipeline(name string, taskRefs ...string) *unstructured.Unstructured {
+	var tasks []interface{}
+	for _, ref := range taskRefs {
+		tasks = append(tasks, map[string]interface{}{"name": ref, "taskRef": map[string]interface{}{"name": ref}})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tekton.dev/v1",
+		"kind":       "Pipeline",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"tasks": tasks},
+	}}
+}
+
+func TestValidateManifestsTaskRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		objs []manifestObject
+		want []string
+	}{
+		{
+			name: "every taskRef installed",
+			objs: []manifestObject{
+				{fileName: "pipelines/ci.yml", obj: testPipeline("ci", "lint", "build")},
+				{fileName: "tasks/lint.yml", obj: testTask("lint")},
+				{fileName: "tasks/build.yml", obj: testTask("build")},
+			},
+		},
+		{
+			name: "taskRef missing from the release",
+			objs: []manifestObject{
+				{fileName: "pipelines/ci.yml", obj: testPipeline("ci", "lint", "build")},
+				{fileName: "tasks/lint.yml", obj: testTask("lint")},
+			},
+			want: []string{"pipelines/ci.yml: Pipeline ci: taskRef build is not a Task of the release"},
+		},
+		{
+			name: "taskRef excluded by spec.tasks",
+			objs: []manifestObject{
+				{fileName: "pipelines/ci.yml", obj: testPipeline("ci", "lint", "build")},
+				{fileName: "tasks/lint.yml", obj: testTask("lint")},
+				{fileName: "tasks/build.yml", obj: testTask("build"), unselected: true},
+			},
+			want: []string{"pipelines/ci.yml: Pipeline ci: taskRef build is excluded by spec.tasks"},
+		},
+		{
+			name: "Task left unchanged after a failed patch",
+			objs: []manifestObject{
+				{fileName: "pipelines/ci.yml", obj: testPipeline("ci", "lint")},
+				{fileName: "tasks/lint.yml", obj: testTask("lint"), patchFailed: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateManifests(context.Background(), tt.objs)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("validateManifests() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
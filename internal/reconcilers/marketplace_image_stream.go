@@ -43,6 +43,11 @@ func NewMarketplaceImageStreamReconciler(client client.Client, log logr.Logger,
 
 // reconcileMarketplaceImageStream will ensure that the Red Hat Marketplace ImageStream is present and up to date.
 func (r *MarketplaceImageStreamReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	// The status reconciler reports the image stream as missing, nothing is imported in ReportOnly mode
+	if reportOnly(pipeline) {
+		return false, nil
+	}
+
 	operatorIndices, err := r.pyxisClient.FindOperatorIndices(ctx, "redhat-marketplace")
 	if err != nil {
 		return true, err
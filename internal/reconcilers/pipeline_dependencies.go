@@ -3,54 +3,77 @@ package reconcilers
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
+	"strings"
 
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/go-logr/logr"
-	securityv1 "github.com/openshift/api/security/v1"
-	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	"sigs.k8s.io/yaml"
 )
 
 const (
-	operatorCIPipelineYml      = "operator-ci-pipeline.yml"
-	operatorHostedPipelineYml  = "operator-hosted-pipeline.yml"
-	operatorReleasePipelineYml = "operator-release-pipeline.yml"
+	operatorCIPipelineYml      = v1alpha1.CIPipelineFile
+	operatorHostedPipelineYml  = v1alpha1.HostedPipelineFile
+	operatorReleasePipelineYml = v1alpha1.ReleasePipelineFile
 	clusterRoleYml             = "openshift-pipeline-sa-scc-role.yml"
 	clusterRoleBindingYml      = "openshift-pipeline-sa-scc-role-bindings.yml"
 	sccYml                     = "openshift-pipelines-custom-scc.yml"
 	ClusterResourceLabel       = "operatorpipelines.certification.redhat.com/cluster-resource"
 	NamespaceLabel             = "operatorpipelines.certification.redhat.com/metadata.name"
+	// FieldManager is the server-side apply field manager the pipeline manifests are applied with
+	FieldManager              = "operator-certification-operator"
+	manifestsAppliedCondition = "ManifestsApplied"
+	manifestsInSyncCondition  = "ManifestsInSync"
+	// updateFieldManager is the field manager the manifests were created and updated with before they were applied,
+	// the API server names it after the manager binary
+	updateFieldManager = "manager"
 )
 
 var (
-	baseManifestsPath     = filepath.Join("ansible", "roles", "operator-pipeline", "templates", "openshift")
-	pipelineManifestsPath = filepath.Join(baseManifestsPath, "pipelines")
-	taskManifestsPath     = filepath.Join(baseManifestsPath, "tasks")
+	baseManifestsPath     = path.Join("ansible", "roles", "operator-pipeline", "templates", "openshift")
+	pipelineManifestsPath = path.Join(baseManifestsPath, "pipelines")
+	taskManifestsPath     = path.Join(baseManifestsPath, "tasks")
+	roleDefaultsPath      = path.Join("ansible", "roles", "operator-pipeline", "defaults", "main.yml")
 )
 
 type PipelineDependenciesReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	Source ManifestSource
+	// conflicts lists the objects that were not applied because fields are managed by another field manager
+	conflicts []string
+	// drift lists the objects that differ from the manifests after this reconcile
+	drift []v1alpha1.ObjectDrift
+	// inventory lists the objects of the manifests selected for the pipeline
+	inventory []v1alpha1.InventoryEntry
+	// patchFailures lists the objects the user supplied patches could not be applied to
+	patchFailures []v1alpha1.PatchFailure
+	// deprecated lists the Tekton objects that rely on v1beta1 fields v1 no longer has
+	deprecated []string
+	// rewrittenImages maps the image references rewritten by the image mirrors to their mirror
+	rewrittenImages map[string]string
+	// unmappedImages are the image references no image mirror matched
+	unmappedImages map[string]bool
 }
 
-func NewPipeDependenciesReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *PipelineDependenciesReconciler {
+func NewPipeDependenciesReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme, source ManifestSource) *PipelineDependenciesReconciler {
 	return &PipelineDependenciesReconciler{
 		Client: client,
 		Log:    log,
 		Scheme: scheme,
+		Source: source,
 	}
 }
 
@@ -60,197 +83,325 @@ func (r *PipelineDependenciesReconciler) Reconcile(ctx context.Context, pipeline
 	// ref: https://github.com/redhat-openshift-ecosystem/certification-releases/blob/main/4.9/ga/ci-pipeline.md#step-6---install-the-certification-pipeline-and-dependencies-into-the-cluster
 	log := r.Log.WithName("pipelinedependencies")
 
-	gitPath := filepath.Join(os.Getenv("GIT_REPO_PATH"), "operator-pipeline")
-	// This will check that the repo has been cloned and is valid
-	_, err := git.PlainOpen(gitPath)
+	// Manifests are read from the source selected for this pipeline, for git that is the checkout of the
+	// commit resolved for this pipeline, so pipelines requesting different releases never see each other's manifests
+	source := r.Source
+	manifests, err := source.Manifests(ctx, pipeline)
 	if err != nil {
-		log.Error(err, "Pipelines repo is not valid")
+		log.Error(err, fmt.Sprintf("Pipelines manifests are not available from %s", source.Describe(pipeline)))
 		return true, err
 	}
 
-	pipelineManifestsPath := filepath.Join(gitPath, pipelineManifestsPath)
-
-	if err := r.applyOrDeletePipeline(ctx, pipeline, pipeline.Spec.ApplyCIPipeline, filepath.Join(pipelineManifestsPath, operatorCIPipelineYml)); err != nil {
-		return true, err
+	// Failing to clean up unused archive extractions should not block the reconciliation, it is retried on the next one
+	if err := pruneManifestArchives(ctx, r.Client, pipeline); err != nil {
+		log.Error(err, "Couldn't remove unused manifest archive extractions")
 	}
 
-	if err := r.applyOrDeletePipeline(ctx, pipeline, pipeline.Spec.ApplyHostedPipeline, filepath.Join(pipelineManifestsPath, operatorHostedPipelineYml)); err != nil {
+	objs, invalid, err := r.loadObjects(ctx, manifests, pipeline)
+	if err != nil {
 		return true, err
 	}
 
-	if err := r.applyOrDeletePipeline(ctx, pipeline, pipeline.Spec.ApplyReleasePipeline, filepath.Join(pipelineManifestsPath, operatorReleasePipelineYml)); err != nil {
+	// Nothing is applied unless every object is valid, so the namespace never mixes objects of two releases
+	invalid = append(invalid, validateManifests(ctx, objs)...)
+	if len(invalid) > 0 {
+		setPipelineCondition(pipeline, manifestsValidCondition, false, "Invalid", strings.Join(invalid, "; "))
+		err := fmt.Errorf("%w: %d problems found", errors.ErrManifestsInvalid, len(invalid))
+		log.Error(err, "Pipelines manifests were not applied")
 		return true, err
 	}
+	setPipelineCondition(pipeline, manifestsValidCondition, true, "AsExpected",
+		fmt.Sprintf("%d objects validated", len(objs)))
 
-	taskManifestsPath := filepath.Join(gitPath, taskManifestsPath)
-
-	tasks, err := os.ReadDir(taskManifestsPath)
-	if err != nil {
-		log.Error(err, "could not read tasks directory")
-		return true, err
-	}
+	for _, o := range objs {
+		if o.unselected {
+			continue
+		}
+		if o.patchFailed {
+			// The object stays as it is in the cluster until its patches are fixed, so it must not be pruned
+			r.inventory = append(r.inventory, inventoryEntry(o.obj))
+			continue
+		}
 
-	for _, task := range tasks {
-		if !task.IsDir() {
-			if err := r.applyManifests(ctx, filepath.Join(taskManifestsPath, task.Name()), pipeline, new(tekton.Task), false); err != nil {
-				return true, err
-			}
+		if err := r.applyObject(ctx, pipeline, o.obj); err != nil {
+			log.Error(err, fmt.Sprintf("failed to apply %s %s from file: %s", o.obj.GetKind(), o.obj.GetName(), o.fileName))
+			return true, err
 		}
 	}
 
-	if err := r.applyManifests(ctx, filepath.Join(gitPath, baseManifestsPath, sccYml), pipeline, new(securityv1.SecurityContextConstraints), true); err != nil {
+	// Disabled pipelines and objects gone from the release are no longer in the inventory and get removed
+	if err := r.pruneInventory(ctx, pipeline, manifests); err != nil {
 		return true, err
 	}
 
-	if err := r.applyManifests(ctx, filepath.Join(gitPath, baseManifestsPath, clusterRoleYml), pipeline, new(rbacv1.ClusterRole), true); err != nil {
-		return true, err
+	pipeline.Status.PatchFailures = r.patchFailures
+	if len(r.patchFailures) > 0 {
+		setPipelineCondition(pipeline, patchesAppliedCondition, false, "PatchFailed",
+			fmt.Sprintf("%d objects could not be patched and were left unchanged", len(r.patchFailures)))
+	} else {
+		setPipelineCondition(pipeline, patchesAppliedCondition, true, "AsExpected",
+			fmt.Sprintf("%d patches requested, all applied", len(pipeline.Spec.Patches)))
 	}
 
-	// apply the cluster role binding with modifications below
-	tmpFile, err := r.modifyAndSaveTempClusterRoleBinding(ctx, filepath.Join(gitPath, baseManifestsPath, clusterRoleBindingYml), pipeline, new(rbacv1.ClusterRoleBinding))
-	if err != nil {
-		return true, err
+	pipeline.Status.RewrittenImages, pipeline.Status.UnmappedImages = r.imageStatus()
+
+	if len(r.deprecated) > 0 {
+		setPipelineCondition(pipeline, manifestsCurrentCondition, false, "DeprecatedFields",
+			fmt.Sprintf("The release relies on Tekton v1beta1 fields removed from v1: %s", strings.Join(r.deprecated, ", ")))
+	} else {
+		setPipelineCondition(pipeline, manifestsCurrentCondition, true, "AsExpected", "The release uses no deprecated Tekton fields")
 	}
-	defer os.Remove(tmpFile)
 
-	if err := r.applyManifests(ctx, tmpFile, pipeline, new(rbacv1.ClusterRoleBinding), true); err != nil {
-		return true, err
+	pipeline.Status.Drift = r.drift
+	if len(r.drift) > 0 {
+		setPipelineCondition(pipeline, manifestsInSyncCondition, false, "Drifted",
+			fmt.Sprintf("%d objects differ from the manifests", len(r.drift)))
+	} else {
+		setPipelineCondition(pipeline, manifestsInSyncCondition, true, "AsExpected", "Live objects match the manifests")
 	}
 
-	return false, nil
-}
+	if reportOnly(pipeline) {
+		setPipelineCondition(pipeline, manifestsAppliedCondition, false, "ReportOnly",
+			"Manifests are not applied in ReportOnly mode")
+		return false, nil
+	}
 
-func (r *PipelineDependenciesReconciler) applyOrDeletePipeline(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, applyManifest bool, yamlPath string) error {
-	if applyManifest {
-		return r.applyManifests(ctx, yamlPath, pipeline, new(tekton.Pipeline), false)
+	// Conflicts need someone to either give up the fields or force the apply, retrying would not resolve them
+	if len(r.conflicts) > 0 {
+		setPipelineCondition(pipeline, manifestsAppliedCondition, false, "Conflict",
+			fmt.Sprintf("Fields managed by another field manager were not applied, set forceApply to take ownership: %s",
+				strings.Join(r.conflicts, "; ")))
+		return false, nil
 	}
-	return r.deleteManifests(ctx, yamlPath, pipeline, new(tekton.Pipeline))
+	setPipelineCondition(pipeline, manifestsAppliedCondition, true, "AsExpected",
+		fmt.Sprintf("Manifests applied with field manager %s", FieldManager))
+
+	// Everything was applied, so the commit can be rolled back to later on
+	recordAppliedRelease(pipeline, metav1.Now())
+
+	return false, nil
 }
 
-func (r *PipelineDependenciesReconciler) applyManifests(ctx context.Context, fileName string, owner, obj client.Object, addClusterResourceLabel bool) error {
-	log := r.Log.WithName("applyManifests")
+// loadObjects loads the objects of the selected pipelines, of the Tasks they need and of the files every pipeline
+// depends on, with the patches and image mirrors of the pipeline applied. Files and pipelines that cannot be loaded
+// are returned with the reason, so every problem of a release is reported at once.
+func (r *PipelineDependenciesReconciler) loadObjects(ctx context.Context, manifests fs.FS, pipeline *v1alpha1.OperatorPipeline) ([]manifestObject, []string, error) {
+	log := r.Log.WithName("loadObjects")
 
-	b, err := os.ReadFile(fileName)
+	vars, err := templateVariables(manifests, pipeline)
 	if err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't read manifest file for: %s", fileName))
-		return err
+		log.Error(err, "Couldn't read the template variables")
+		return nil, nil, err
 	}
 
-	if err = yamlutil.Unmarshal(b, &obj); err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't unmarshall yaml file for: %s", fileName))
-		return err
+	selected, err := selectPipelines(manifests, r.RESTMapper(), vars, pipeline)
+	if err != nil {
+		log.Error(err, "could not read pipelines directory")
+		return nil, nil, err
 	}
 
-	// adding cluster resource label for scc, clusterrole, clusterrolebinding, this is so they can be selected
-	// by label on deletion
-	if addClusterResourceLabel {
-		labels := obj.GetLabels()
-		if labels == nil {
-			labels = make(map[string]string)
+	var objs []manifestObject
+	var invalid []string
+	var pipelines []*unstructured.Unstructured
+	for _, s := range selected {
+		if len(s.problem) > 0 {
+			invalid = append(invalid, s.problem)
+			continue
+		}
+		prepared := r.prepareObjects(ctx, pipeline, s.fileName, s.objs)
+		for _, o := range prepared {
+			pipelines = append(pipelines, o.obj)
 		}
+		objs = append(objs, prepared...)
+	}
 
-		labels[ClusterResourceLabel] = "true"
+	taskFiles, err := fs.ReadDir(manifests, taskManifestsPath)
+	if err != nil {
+		log.Error(err, "could not read tasks directory")
+		return nil, nil, err
+	}
 
-		obj.SetLabels(labels)
+	loadedTasks := map[string][]*unstructured.Unstructured{}
+	var tasks []*unstructured.Unstructured
+	for _, task := range taskFiles {
+		if task.IsDir() {
+			continue
+		}
+		fileName := path.Join(taskManifestsPath, task.Name())
+		loaded, err := loadManifests(manifests, fileName, r.RESTMapper(), vars, pipeline.Namespace)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Couldn't load manifest file for: %s", fileName))
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		loadedTasks[fileName] = loaded
+		for _, obj := range loaded {
+			if isTask(obj) {
+				tasks = append(tasks, obj)
+			}
+		}
 	}
 
-	obj.SetNamespace(owner.GetNamespace())
-	err = r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj)
+	// Only the Tasks the selected pipelines need are applied, the others are left out of the inventory and pruned
+	selectedTasks, err := selectTasks(pipelines, tasks, pipeline.Spec.Tasks)
+	if err != nil {
+		invalid = append(invalid, err.Error())
+	}
+	for _, task := range taskFiles {
+		fileName := path.Join(taskManifestsPath, task.Name())
+		for _, obj := range loadedTasks[fileName] {
+			if isTask(obj) && !selectedTasks[obj.GetName()] {
+				objs = append(objs, manifestObject{fileName: fileName, obj: obj, unselected: true})
+				continue
+			}
+			objs = append(objs, r.prepareObjects(ctx, pipeline, fileName, []*unstructured.Unstructured{obj})...)
+		}
+	}
 
-	if len(obj.GetUID()) > 0 {
-		if err := r.Update(ctx, obj); err != nil {
-			log.Error(err, fmt.Sprintf("failed to update pipeline resource for file: %s", fileName))
-			return err
+	for _, fileName := range []string{
+		path.Join(baseManifestsPath, sccYml),
+		path.Join(baseManifestsPath, clusterRoleYml),
+		path.Join(baseManifestsPath, clusterRoleBindingYml),
+	} {
+		loaded, err := loadManifests(manifests, fileName, r.RESTMapper(), vars, pipeline.Namespace)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Couldn't load manifest file for: %s", fileName))
+			invalid = append(invalid, err.Error())
+			continue
 		}
+		objs = append(objs, r.prepareObjects(ctx, pipeline, fileName, loaded)...)
 	}
 
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return err
+	return objs, invalid, nil
+}
+
+// prepareObjects applies the patches and image mirrors of the pipeline to the objects loaded from the file.
+func (r *PipelineDependenciesReconciler) prepareObjects(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, fileName string, loaded []*unstructured.Unstructured) []manifestObject {
+	var objs []manifestObject
+	for _, obj := range loaded {
+		if usesDeprecatedTektonFields(obj) {
+			r.deprecated = append(r.deprecated, fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName()))
 		}
-		_ = controllerutil.SetControllerReference(owner, obj, r.Scheme)
-		if err := r.Create(ctx, obj); err != nil {
-			log.Error(err, fmt.Sprintf("failed to create pipeline resource for file: %s", fileName))
-			return err
+
+		patched, ok := r.patchObject(ctx, pipeline, obj)
+		if !ok {
+			objs = append(objs, manifestObject{fileName: fileName, obj: obj, patchFailed: true})
+			continue
 		}
+		r.mirrorImages(pipeline, patched)
+
+		objs = append(objs, manifestObject{fileName: fileName, obj: patched})
 	}
+	return objs
+}
 
-	return nil
+// reportOnly returns whether the pipeline only reports the drift of the live objects without changing them.
+func reportOnly(pipeline *v1alpha1.OperatorPipeline) bool {
+	return pipeline.Spec.ReconcileMode == v1alpha1.ReconcileModeReportOnly
 }
 
-func (r *PipelineDependenciesReconciler) deleteManifests(ctx context.Context, fileName string, owner, obj client.Object) error {
-	log := r.Log.WithName("deleteManifests")
-	b, err := os.ReadFile(fileName)
-	if err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't read manifest file for: %s", fileName))
+func (r *PipelineDependenciesReconciler) applyObject(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, obj *unstructured.Unstructured) error {
+	// adding cluster resource label for cluster-scoped objects like the scc, clusterrole and clusterrolebinding,
+	// this is so they can be selected by label on deletion
+	if len(obj.GetNamespace()) == 0 {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		labels[ClusterResourceLabel] = "true"
+		// cluster role bindings are per namespace, so they are selected by the namespace too
+		if obj.GetKind() == "ClusterRoleBinding" {
+			labels[NamespaceLabel] = pipeline.Namespace
+		}
+
+		obj.SetLabels(labels)
+	} else if err := r.setControllerReference(ctx, pipeline, obj); err != nil {
 		return err
 	}
 
-	if err = yamlutil.Unmarshal(b, &obj); err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't unmarshall yaml file for: %s", fileName))
+	r.inventory = append(r.inventory, inventoryEntry(obj))
+
+	drift, err := objectDrift(ctx, r.Client, obj, v1alpha1.DriftModified)
+	if err != nil {
 		return err
 	}
+	if reportOnly(pipeline) {
+		if drift != nil {
+			r.drift = append(r.drift, *drift)
+		}
+		return nil
+	}
+	if drift != nil {
+		r.Log.Info(fmt.Sprintf("correcting drift of %s %s", obj.GetKind(), obj.GetName()), "type", drift.Type, "fields", drift.Fields)
+	}
 
-	obj.SetNamespace(owner.GetNamespace())
-	if err := r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); err != nil && !errors.IsNotFound(err) {
+	if err := r.migrateManagedFields(ctx, obj); err != nil {
 		return err
-	} else if errors.IsNotFound(err) {
-		return nil
 	}
 
-	if err := r.Delete(ctx, obj); err != nil {
-		log.Error(err, fmt.Sprintf("failed to delete pipeline resource for file: %s", fileName))
+	opts := []client.ApplyOption{client.FieldOwner(FieldManager)}
+	if pipeline.Spec.ForceApply {
+		opts = append(opts, client.ForceOwnership)
+	}
+
+	if err := r.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj), opts...); err != nil {
+		if apierrors.IsConflict(err) {
+			r.Log.Info(fmt.Sprintf("conflicts applying %s %s", obj.GetKind(), obj.GetName()), "error", err.Error())
+			r.conflicts = append(r.conflicts, fmt.Sprintf("%s %s: %v", obj.GetKind(), obj.GetName(), err))
+			if drift != nil {
+				r.drift = append(r.drift, *drift)
+			}
+			return nil
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (r *PipelineDependenciesReconciler) modifyAndSaveTempClusterRoleBinding(_ context.Context, fileName string, owner, obj client.Object) (string, error) {
-	log := r.Log.WithName("modifyAndSaveTempClusterRoleBinding")
-
-	b, err := os.ReadFile(fileName)
-	if err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't read manifest file for: %s", fileName))
-		return "", err
+// migrateManagedFields hands the fields the operator set with Update over to its apply field manager. Applying would
+// otherwise conflict with the operator's own updates, and fields dropped from the manifests would be kept. Fields
+// other managers set are left to them.
+func (r *PipelineDependenciesReconciler) migrateManagedFields(ctx context.Context, obj *unstructured.Unstructured) error {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := r.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if apierrors.IsNotFound(err) {
+		return nil
 	}
-
-	if err = yamlutil.Unmarshal(b, &obj); err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't unmarshal yaml file for: %s", fileName))
-		return "", err
+	if err != nil {
+		return err
 	}
 
-	// type asserting to ensure proper kind inorder to update specific values before updating/creating in cluster
-	crb, ok := obj.(*rbacv1.ClusterRoleBinding)
-	if !ok {
-		return "", fmt.Errorf("could not type assert client.Object as ClusterRoleBinding")
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(live, sets.New(updateFieldManager), FieldManager)
+	if err != nil || patch == nil {
+		return err
 	}
+	r.Log.Info(fmt.Sprintf("migrating the managed fields of %s %s to server-side apply", obj.GetKind(), obj.GetName()))
+	return r.Patch(ctx, live, client.RawPatch(types.JSONPatchType, patch))
+}
 
-	// updating the below values, since the yaml read in is templated and incomplete
-	crb.Name = fmt.Sprintf("pipeline-%s-pipelines-custom-scc", owner.GetNamespace())
-	crb.Subjects[0].Namespace = owner.GetNamespace()
-
-	// adding additional label so it can be selected by label on deletion
-	metav1.SetMetaDataLabel(&crb.ObjectMeta, NamespaceLabel, owner.GetNamespace())
-
-	b, err = yaml.Marshal(crb)
+// setControllerReference makes the pipeline the controller of the object, unless another OperatorPipeline in the
+// namespace already is. Pipelines sharing the same manifests would otherwise keep taking the objects from each other.
+func (r *PipelineDependenciesReconciler) setControllerReference(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, obj *unstructured.Unstructured) error {
+	existing, err := r.Scheme.New(obj.GroupVersionKind())
 	if err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't marshal ClusterRoleBinding for: %s", fileName))
-		return "", err
+		return err
+	}
+	current, ok := existing.(client.Object)
+	if !ok {
+		return fmt.Errorf("%s is not a client.Object", obj.GroupVersionKind())
 	}
 
-	// creating temp file in a temp directory
-	tmpFile, err := os.CreateTemp("", "cluster-role-binding-*.yaml")
-	if err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't create temp file for: %s", fileName))
-		return "", err
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil && !apierrors.IsNotFound(err) {
+		return err
 	}
 
-	_, err = tmpFile.Write(b)
-	if err != nil {
-		log.Error(err, fmt.Sprintf("Couldn't write manifest file for: %s", fileName))
-		return "", err
+	if ref := metav1.GetControllerOf(current); ref != nil {
+		obj.SetOwnerReferences([]metav1.OwnerReference{*ref})
+		return nil
 	}
 
-	return tmpFile.Name(), nil
+	return controllerutil.SetControllerReference(pipeline, obj, r.Scheme)
 }
@@ -0,0 +1,111 @@
+package reconcilers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPipelineDependenciesReconcilerApplyObject(t *testing.T) {
+	tests := []struct {
+		name          string
+		manager       string
+		forceApply    bool
+		wantConflicts int
+		wantVerbs     []string
+	}{
+		{
+			name:      "fields set by the operator with Update are migrated",
+			manager:   updateFieldManager,
+			wantVerbs: []string{"use"},
+		},
+		{
+			name:          "fields set by another manager conflict",
+			manager:       "kubectl-edit",
+			wantConflicts: 1,
+			wantVerbs:     []string{"get"},
+		},
+		{
+			name:       "fields set by another manager are forced",
+			manager:    "kubectl-edit",
+			forceApply: true,
+			wantVerbs:  []string{"use"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithReturnManagedFields().Build()
+
+			live := &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "pipelines-scc-clusterrole"},
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups: []string{"security.openshift.io"},
+					Resources: []string{"securitycontextconstraints"},
+					Verbs:     []string{"get"},
+				}},
+			}
+			if err := c.Create(ctx, live, client.FieldOwner(tt.manager)); err != nil {
+				t.Fatal(err)
+			}
+
+			desired := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "ClusterRole",
+				"metadata":   map[string]interface{}{"name": "pipelines-scc-clusterrole"},
+				"rules": []interface{}{map[string]interface{}{
+					"apiGroups": []interface{}{"security.openshift.io"},
+					"resources": []interface{}{"securitycontextconstraints"},
+					"verbs":     []interface{}{"use"},
+				}},
+			}}
+			pipeline := &v1alpha1.OperatorPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci"},
+				Spec:       v1alpha1.OperatorPipelineSpec{ForceApply: tt.forceApply},
+			}
+			r := NewPipeDependenciesReconciler(c, logr.Discard(), scheme, NewResolvedManifests(c))
+			if err := r.applyObject(ctx, pipeline, desired); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(r.conflicts) != tt.wantConflicts {
+				t.Fatalf("conflicts %v, want %d", r.conflicts, tt.wantConflicts)
+			}
+			// Conflicting objects are reported as drifted
+			if len(r.drift) != tt.wantConflicts {
+				t.Fatalf("drift %+v, want %d", r.drift, tt.wantConflicts)
+			}
+
+			got := &rbacv1.ClusterRole{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(live), got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Rules[0].Verbs, tt.wantVerbs) {
+				t.Fatalf("verbs %v, want %v", got.Rules[0].Verbs, tt.wantVerbs)
+			}
+			for _, entry := range got.ManagedFields {
+				if entry.Manager == updateFieldManager {
+					t.Fatalf("fields still managed by %s with %s", entry.Manager, entry.Operation)
+				}
+			}
+		})
+	}
+}
@@ -1,124 +1,462 @@
 package reconcilers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/go-logr/logr"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	operatorPipelinesRepo = "https://github.com/redhat-openshift-ecosystem/operator-pipelines.git"
+	knownHostsSecretKey   = "known_hosts"
+	gitRepoReadyCondition = "GitRepoReady"
+	// gitHostKeyVerifiedCondition warns that the SSH host key of the repository is not verified
+	gitHostKeyVerifiedCondition = "GitHostKeyVerified"
+	// staleTmpAge is how old a temporary checkout or extraction must be before it is considered abandoned
+	staleTmpAge = time.Hour
 )
 
+func init() {
+	// The manager image does not ship a git binary, so serve file:// remotes with go-git's
+	// own upload-pack implementation instead of shelling out to git-upload-pack.
+	gitclient.InstallProtocol("file", server.DefaultServer)
+}
+
 type PipelineGitRepoReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	Cache  *RepositoryCache
 }
 
-func NewPipelineGitRepoReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *PipelineGitRepoReconciler {
+func NewPipelineGitRepoReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme, cache *RepositoryCache) *PipelineGitRepoReconciler {
 	return &PipelineGitRepoReconciler{
 		Client: client,
 		Log:    log,
 		Scheme: scheme,
+		Cache:  cache,
 	}
 }
 
-func (r *PipelineGitRepoReconciler) Reconcile(_ context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+func (r *PipelineGitRepoReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
 	log := r.Log.WithName("gitrepo")
-	gitMount, ok := os.LookupEnv("GIT_REPO_PATH")
-	if !ok {
-		log.Error(errors.ErrGitRepoPathNotSpecified, "could not find envvar GIT_REPO_PATH")
-		return true, errors.ErrGitRepoPathNotSpecified
+	if !usesGitManifestSource(pipeline) {
+		// The manifests are provided some other way, there is no repository to report on
+		meta.RemoveStatusCondition(&pipeline.Status.Conditions, gitRepoReadyCondition)
+		meta.RemoveStatusCondition(&pipeline.Status.Conditions, gitHostKeyVerifiedCondition)
+		return false, nil
+	}
+
+	gitPath, err := pipelinesRepoPath(pipeline)
+	if err != nil {
+		log.Error(err, "could not find envvar GIT_REPO_PATH")
+		setGitRepoCondition(pipeline, false, "NotFound", "Local repo path not configured")
+		return true, err
+	}
+
+	remote := pipelinesRepoURL(pipeline)
+	auth, err := r.repoAuth(ctx, pipeline)
+	if err != nil {
+		log.Error(err, "Couldn't load the credentials for operator-pipelines")
+		setGitRepoCondition(pipeline, false, "CredentialsInvalid",
+			fmt.Sprintf("Credentials for %s could not be loaded: %v", remote, err))
+		return true, err
+	}
+
+	keys, err := r.trustedKeys(ctx, pipeline)
+	if err != nil {
+		log.Error(err, "Couldn't load the trusted keys for operator-pipelines")
+		setGitRepoCondition(pipeline, false, "TrustedKeysInvalid", fmt.Sprintf("Trusted keys could not be loaded: %v", err))
+		return true, err
+	}
+
+	repo, err := r.Cache.cloneOrPullRepo(ctx, gitPath, remote, auth, pipeline.Spec.OperatorPipelinesRelease)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Couldn't clone the repository for operator-pipelines from %s", remote))
+		setGitRepoCondition(pipeline, false, "FetchFailed", fmt.Sprintf("Git repo %s could not be fetched: %v", remote, err))
+		return true, err
 	}
-	gitPath := filepath.Join(gitMount, "operator-pipeline")
-	hash, err := cloneOrPullRepo(gitPath, pipeline.Spec.OperatorPipelinesRelease)
+
+	resolved, err := resolveRelease(repo, pipeline.Spec.OperatorPipelinesRelease)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Couldn't resolve the operator-pipelines release %s", pipeline.Spec.OperatorPipelinesRelease))
+		setGitRepoCondition(pipeline, false, "ReleaseNotFound", err.Error())
+		return true, err
+	}
+	resolved.Repository = remote
+
+	// A rollback or the update policy can hold the pipeline on a commit from the history
+	pipeline.Status.PendingUpdate = nil
+	if len(pipeline.Spec.RollbackToCommit) > 0 {
+		target, err := rollbackTarget(pipeline)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Couldn't roll back to operator-pipelines commit %s", pipeline.Spec.RollbackToCommit))
+			setGitRepoCondition(pipeline, false, "RollbackNotFound", err.Error())
+			return true, err
+		}
+		log.Info(fmt.Sprintf("operator-pipelines rolled back to commit %s", target.Commit))
+		resolved = target
+	} else if !rolloutAllowed(pipeline, resolved) {
+		log.Info(fmt.Sprintf("operator-pipelines commit %s is not rolled out, update policy is %s",
+			resolved.Commit, pipeline.Spec.UpdatePolicy))
+		pipeline.Status.PendingUpdate = resolved
+		resolved = pipeline.Status.ResolvedRelease
+	}
+
+	// Every commit is verified before it is checked out, whichever path selected it, so neither a rollback nor a
+	// change of the trusted keys can bring back content that is not signed by a trusted key. Refusing to check out
+	// unverified content leaves the last verified commit in place.
+	if keys != nil {
+		if err := verifyRelease(repo, resolved, keys); err != nil {
+			log.Error(err, fmt.Sprintf("Refusing operator-pipelines %s %s", strings.ToLower(string(resolved.Type)), resolved.Name))
+			setGitRepoCondition(pipeline, false, "SignatureInvalid", err.Error())
+			return true, err
+		}
+	}
+	hash := resolved.Commit
+
+	if tags, err := r.Cache.tags(gitPath); err != nil {
+		log.Error(err, "Couldn't list the operator-pipelines tags")
+	} else {
+		pipeline.Status.AvailableUpdates = availableUpdates(resolved, tags)
+	}
+
+	checkoutPath, err := pipelinesCheckoutPath(hash)
 	if err != nil {
-		log.Error(err, "Couldn't clone the repository for operator-pipelines")
+		log.Error(err, "could not find envvar GIT_REPO_PATH")
+		setGitRepoCondition(pipeline, false, "NotFound", "Local repo path not configured")
 		return true, err
 	}
-	log.Info(fmt.Sprintf("Hash of operator-pipelines HEAD: %s", hash))
+
+	if err := checkoutCommit(repo, plumbing.NewHash(hash), checkoutPath); err != nil {
+		log.Error(err, fmt.Sprintf("Couldn't check out operator-pipelines commit %s", hash))
+		setGitRepoCondition(pipeline, false, "CheckoutFailed", fmt.Sprintf("Commit %s could not be checked out: %v", hash, err))
+		return true, err
+	}
+	log.Info(fmt.Sprintf("operator-pipelines commit %s checked out at %s", hash, checkoutPath))
+
+	// The dependencies and status reconcilers read the manifests from the checkout of this commit
+	pipeline.Status.PipelinesRepoHash = hash
+	pipeline.Status.ResolvedRelease = resolved
+	message := fmt.Sprintf("Git repo %s is ready at %s %s (%s)", remote, strings.ToLower(string(resolved.Type)), resolved.Name, hash)
+	if len(pipeline.Spec.RollbackToCommit) > 0 {
+		message += ", rolled back until rollbackToCommit is cleared"
+	}
+	if pending := pipeline.Status.PendingUpdate; pending != nil {
+		message += fmt.Sprintf(", commit %s is awaiting rollout with the %s update policy", pending.Commit, pipeline.Spec.UpdatePolicy)
+	}
+	setGitRepoCondition(pipeline, true, "AsExpected", message)
+
+	// Failing to clean up unused checkouts should not block the reconciliation, it is retried on the next one
+	if err := r.pruneCheckouts(ctx, pipeline); err != nil {
+		log.Error(err, "Couldn't remove unused operator-pipelines checkouts")
+	}
 
 	return false, nil
 }
 
-func cloneOrPullRepo(targetPath string, pipelineRelease string) (string, error) {
-	// Try to clone first
-	r, err := git.PlainClone(targetPath, false, &git.CloneOptions{
-		URL: operatorPipelinesRepo,
-	})
-	if err != nil && err != git.ErrRepositoryAlreadyExists {
-		return "", err
+// setGitRepoCondition reports the state of the operator-pipelines repository. The git reconciler owns this
+// condition since it knows why the repository could not be used, the status reconciler commits it.
+func setGitRepoCondition(pipeline *v1alpha1.OperatorPipeline, ready bool, reason, message string) {
+	setPipelineCondition(pipeline, gitRepoReadyCondition, ready, reason, message)
+}
+
+// trustedKeys loads the keys the operator-pipelines commits and tags must be signed with.
+// It returns nil when the pipeline does not require signature verification.
+func (r *PipelineGitRepoReconciler) trustedKeys(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (*trustedKeys, error) {
+	name := pipeline.Spec.TrustedKeysConfigMapName
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: name}, cm); err != nil {
+		return nil, err
+	}
+
+	return trustedKeysFromConfigMap(cm)
+}
+
+// pruneCheckouts removes the checkouts that no OperatorPipeline in the cluster is using anymore. Checkouts still in
+// progress are left alone.
+func (r *PipelineGitRepoReconciler) pruneCheckouts(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) error {
+	pipelines := &v1alpha1.OperatorPipelineList{}
+	if err := r.List(ctx, pipelines); err != nil {
+		return err
 	}
-	// The directory is already there, so let's just update to latest
-	if r == nil && err == git.ErrRepositoryAlreadyExists {
-		var err error
-		r, err = git.PlainOpen(targetPath)
+
+	inUse := map[string]bool{pipeline.Status.PipelinesRepoHash: true}
+	for _, p := range pipelines.Items {
+		inUse[p.Status.PipelinesRepoHash] = true
+	}
+
+	checkoutsPath, err := pipelinesCheckoutPath("")
+	if err != nil {
+		return err
+	}
+
+	return pruneUnused(checkoutsPath, inUse)
+}
+
+// pruneUnused removes the entries of the directory that are not in use. The temporary directories entries are
+// written to before they are renamed in place are left alone while they may still be written to, and removed
+// once they are older than staleTmpAge, a reconcile that was interrupted leaves them behind.
+func pruneUnused(dir string, inUse map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if inUse[entry.Name()] {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			info, err := entry.Info()
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if time.Since(info.ModTime()) < staleTmpAge {
+				continue
+			}
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repoAuth builds the git credentials from the secret referenced by the pipeline, if any. SSH credentials require
+// known_hosts unless the pipeline opts out of verifying the host key, which is then reported in a condition.
+func (r *PipelineGitRepoReconciler) repoAuth(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (transport.AuthMethod, error) {
+	meta.RemoveStatusCondition(&pipeline.Status.Conditions, gitHostKeyVerifiedCondition)
+	secretName := pipeline.Spec.OperatorPipelinesRepositorySecretName
+	if len(secretName) == 0 {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: secretName}, secret); err != nil {
+		return nil, err
+	}
+
+	if key, ok := secret.Data[corev1.SSHAuthPrivateKey]; ok {
+		user := "git"
+		if ep, err := transport.NewEndpoint(pipelinesRepoURL(pipeline)); err == nil && len(ep.User) > 0 {
+			user = ep.User
+		}
+		auth, err := gitssh.NewPublicKeys(user, key, "")
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		knownHosts := secret.Data[knownHostsSecretKey]
+		if len(knownHosts) == 0 && !pipeline.Spec.InsecureIgnoreHostKey {
+			return nil, fmt.Errorf("%w: %s must contain %s along with %s, or insecureIgnoreHostKey must be set",
+				errors.ErrInvalidSecret, secretName, knownHostsSecretKey, corev1.SSHAuthPrivateKey)
+		}
+		if len(knownHosts) == 0 {
+			auth.HostKeyCallback = ssh.InsecureIgnoreHostKey() //nolint:gosec // explicitly requested in the spec
+			setPipelineCondition(pipeline, gitHostKeyVerifiedCondition, false, "InsecureIgnoreHostKey",
+				fmt.Sprintf("The SSH host key of %s is not verified, add %s to secret %s", pipelinesRepoURL(pipeline), knownHostsSecretKey, secretName))
+			return auth, nil
 		}
+		auth.HostKeyCallback, err = knownHostsCallback(knownHosts)
+		if err != nil {
+			return nil, err
+		}
+		return auth, nil
 	}
 
-	// If r is nil both clone and open were unsuccessful, returning err to avoid a later panic
-	if r == nil {
-		return "", fmt.Errorf("could not clone or open repo")
+	if password, ok := secret.Data[corev1.BasicAuthPasswordKey]; ok {
+		return &githttp.BasicAuth{
+			Username: string(secret.Data[corev1.BasicAuthUsernameKey]),
+			Password: string(password),
+		}, nil
 	}
 
-	// Fetching to ensure repo on disk is up to date before we checkout
-	if err := r.Fetch(&git.FetchOptions{Tags: git.AllTags}); err != nil && err != git.NoErrAlreadyUpToDate {
-		return "", err
+	return nil, fmt.Errorf("%w: %s must contain either %s or %s", errors.ErrInvalidSecret, secretName,
+		corev1.SSHAuthPrivateKey, corev1.BasicAuthPasswordKey)
+}
+
+// knownHostsCallback accepts only the host keys listed in the given known_hosts data. A line that is not a valid
+// entry is an error rather than silently dropping the keys after it.
+func knownHostsCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	var keys [][]byte
+	for i, line := range bytes.Split(knownHosts, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		_, _, key, _, _, err := ssh.ParseKnownHosts(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s line %d: %v", errors.ErrInvalidSecret, knownHostsSecretKey, i+1, err)
+		}
+		keys = append(keys, key.Marshal())
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no valid entries in %s", errors.ErrInvalidSecret, knownHostsSecretKey)
 	}
 
-	var ref *plumbing.Reference
-	iter, err := r.References()
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		for _, known := range keys {
+			if bytes.Equal(known, key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key for %s is not in %s", hostname, knownHostsSecretKey)
+	}, nil
+}
+
+// pipelinesRepoURL returns the operator-pipelines remote requested by the pipeline.
+func pipelinesRepoURL(pipeline *v1alpha1.OperatorPipeline) string {
+	if len(pipeline.Spec.OperatorPipelinesRepository) > 0 {
+		return pipeline.Spec.OperatorPipelinesRepository
+	}
+	return operatorPipelinesRepo
+}
+
+// pipelinesRepoPath returns where the object store for the pipeline's operator-pipelines remote is kept on disk.
+// Every remote gets its own store, so pipelines using different forks never share one.
+func pipelinesRepoPath(pipeline *v1alpha1.OperatorPipeline) (string, error) {
+	gitMount, ok := os.LookupEnv("GIT_REPO_PATH")
+	if !ok {
+		return "", errors.ErrGitRepoPathNotSpecified
+	}
+
+	remote := pipelinesRepoURL(pipeline)
+	if remote == operatorPipelinesRepo {
+		return filepath.Join(gitMount, "operator-pipeline"), nil
+	}
+
+	sum := sha256.Sum256([]byte(remote))
+	return filepath.Join(gitMount, "operator-pipeline-"+hex.EncodeToString(sum[:])[:12]), nil
+}
+
+// pipelinesCheckoutPath returns where the operator-pipelines tree of the given commit is checked out on disk.
+// Commits are immutable, so a checkout is shared by every pipeline that resolves to the same commit.
+func pipelinesCheckoutPath(hash string) (string, error) {
+	gitMount, ok := os.LookupEnv("GIT_REPO_PATH")
+	if !ok {
+		return "", errors.ErrGitRepoPathNotSpecified
+	}
+	return filepath.Join(gitMount, "checkouts", hash), nil
+}
+
+// pipelineCheckout returns the checkout of the commit last resolved for the pipeline by the git reconciler.
+func pipelineCheckout(pipeline *v1alpha1.OperatorPipeline) (string, error) {
+	if len(pipeline.Status.PipelinesRepoHash) == 0 {
+		return "", errors.ErrPipelinesRepoNotCheckedOut
+	}
+
+	checkoutPath, err := pipelinesCheckoutPath(pipeline.Status.PipelinesRepoHash)
 	if err != nil {
 		return "", err
 	}
 
-	// Iterating over all the references to ensure the one requested is in the repository
-	// and to use the reference's value later properly checkout the requested branch/tag
-	if err := iter.ForEach(func(reference *plumbing.Reference) error {
-		if !strings.HasSuffix(reference.Name().Short(), pipelineRelease) {
-			return nil
-		}
-		ref = reference
-		return storer.ErrStop
-	}); err != nil {
-		return "", err
+	if _, err := os.Stat(checkoutPath); err != nil {
+		return "", fmt.Errorf("%w: %v", errors.ErrPipelinesRepoNotCheckedOut, err)
 	}
 
-	if ref == nil {
-		return "", fmt.Errorf("requested release is not in repository")
+	return checkoutPath, nil
+}
+
+// checkoutCommit writes the tree of the given commit to targetPath, unless it has been checked out already.
+// The tree is written to a temporary directory first and renamed into place, so a partially written
+// checkout is never picked up by the other reconcilers.
+func checkoutCommit(r *git.Repository, hash plumbing.Hash, targetPath string) error {
+	if _, err := os.Stat(targetPath); err == nil {
+		return nil
 	}
 
-	// Get the worktree
-	w, err := r.Worktree()
+	commit, err := r.CommitObject(hash)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	// Reset the worktree to discard any local changes
-	// This avoids reconciliation errors when anything other than `main` is used in the CR
-	if err := w.Reset(&git.ResetOptions{Mode: git.HardReset}); err != nil {
-		return "", err
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
 	}
 
-	// Checking out the hash value of the branch/tag that was requested
-	if err := w.Checkout(&git.CheckoutOptions{Hash: ref.Hash()}); err != nil {
-		return "", err
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+		return err
 	}
 
-	return ref.Hash().String(), nil
+	tmpPath, err := os.MkdirTemp(filepath.Dir(targetPath), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	if err := tree.Files().ForEach(func(f *object.File) error {
+		return writeTreeFile(tmpPath, f)
+	}); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		// Someone else might have completed the same checkout in the meantime
+		if _, statErr := os.Stat(targetPath); statErr == nil {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+func writeTreeFile(root string, f *object.File) error {
+	path := filepath.Join(root, filepath.FromSlash(f.Name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	contents, err := f.Contents()
+	if err != nil {
+		return err
+	}
+
+	switch f.Mode {
+	case filemode.Symlink:
+		return os.Symlink(contents, path)
+	case filemode.Executable:
+		return os.WriteFile(path, []byte(contents), 0o755)
+	default:
+		return os.WriteFile(path, []byte(contents), 0o644)
+	}
 }
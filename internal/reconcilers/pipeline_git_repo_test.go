@@ -0,0 +1,310 @@
+package reconcilers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	operrors "github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-logr/logr"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestKnownHostsCallback(t *testing.T) {
+	newKey := func() ssh.PublicKey {
+		public, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ssh.NewPublicKey(public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	github, gitlab, unknown := newKey(), newKey(), newKey()
+	entry := func(host string, key ssh.PublicKey) string {
+		return fmt.Sprintf("%s %s", host, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))))
+	}
+
+	tests := []struct {
+		name       string
+		knownHosts string
+		wantErr    string
+		accepted   []ssh.PublicKey
+		rejected   []ssh.PublicKey
+	}{
+		{
+			name:       "every entry is accepted",
+			knownHosts: "# operator-pipelines mirrors\n" + entry("github.com", github) + "\n\n" + entry("gitlab.com", gitlab) + "\n",
+			accepted:   []ssh.PublicKey{github, gitlab},
+			rejected:   []ssh.PublicKey{unknown},
+		},
+		{
+			name:       "malformed line",
+			knownHosts: entry("github.com", github) + "\ngitlab.com ssh-ed25519 not-base64\n" + entry("gitlab.com", gitlab),
+			wantErr:    "known_hosts line 2",
+		},
+		{
+			name:       "only comments",
+			knownHosts: "# no entries\n",
+			wantErr:    "no valid entries in known_hosts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, err := knownHostsCallback([]byte(tt.knownHosts))
+			if len(tt.wantErr) > 0 {
+				if err == nil || !errors.Is(err, operrors.ErrInvalidSecret) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.accepted {
+				if err := callback("github.com:22", nil, key); err != nil {
+					t.Fatalf("known key rejected: %v", err)
+				}
+			}
+			for _, key := range tt.rejected {
+				if err := callback("github.com:22", nil, key); err == nil {
+					t.Fatal("unknown key accepted")
+				}
+			}
+		})
+	}
+}
+
+func TestPipelineGitRepoReconcilerSSHHostKey(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(block)
+	knownHosts := "github.com " + string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	tests := []struct {
+		name        string
+		knownHosts  string
+		insecure    bool
+		wantErr     bool
+		wantWarning bool
+	}{
+		{
+			name:       "known_hosts verifies the host key",
+			knownHosts: knownHosts,
+		},
+		{
+			name:    "known_hosts is required",
+			wantErr: true,
+		},
+		{
+			name:        "host key ignored on request",
+			insecure:    true,
+			wantWarning: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipelines-ssh", Namespace: "operator-ci"},
+				Type:       corev1.SecretTypeSSHAuth,
+				Data:       map[string][]byte{corev1.SSHAuthPrivateKey: privateKey},
+			}
+			if len(tt.knownHosts) > 0 {
+				secret.Data[knownHostsSecretKey] = []byte(tt.knownHosts)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+			pipeline := &v1alpha1.OperatorPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci"},
+				Spec: v1alpha1.OperatorPipelineSpec{
+					OperatorPipelinesRepository:           "git@github.com:redhat-openshift-ecosystem/operator-pipelines.git",
+					OperatorPipelinesRepositorySecretName: secret.Name,
+					InsecureIgnoreHostKey:                 tt.insecure,
+				},
+			}
+			auth, err := NewPipelineGitRepoReconciler(c, logr.Discard(), scheme, nil).repoAuth(context.Background(), pipeline)
+			if tt.wantErr {
+				if !errors.Is(err, operrors.ErrInvalidSecret) {
+					t.Fatalf("error %v, want %v", err, operrors.ErrInvalidSecret)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := auth.(*gitssh.PublicKeys); !ok {
+				t.Fatalf("auth %T, want SSH public keys", auth)
+			}
+
+			condition := meta.FindStatusCondition(pipeline.Status.Conditions, gitHostKeyVerifiedCondition)
+			if (condition != nil) != tt.wantWarning {
+				t.Fatalf("host key condition %+v, want a warning %v", condition, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckoutCommit(t *testing.T) {
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"ansible/roles/operator-pipeline/templates/openshift/pipelines/operator-ci-pipeline.yml": "kind: Pipeline\n",
+		"ansible/roles/operator-pipeline/templates/openshift/tasks/preflight.yml":                "kind: Task\n",
+	}
+	for name, contents := range files {
+		f, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := tree.Commit("release", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkouts := t.TempDir()
+	target := filepath.Join(checkouts, hash.String())
+	if err := checkoutCommit(r, hash, target); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range files {
+		b, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != contents {
+			t.Fatalf("%s = %q, want %q", name, b, contents)
+		}
+	}
+
+	// A completed checkout is reused as it is
+	marker := filepath.Join(target, "marker")
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkoutCommit(r, hash, target); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("checkout was redone: %v", err)
+	}
+
+	entries, err := os.ReadDir(checkouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != hash.String() {
+		t.Fatalf("checkouts %v, want only %s", entries, hash)
+	}
+}
+
+func TestPruneCheckouts(t *testing.T) {
+	gitPath := t.TempDir()
+	t.Setenv("GIT_REPO_PATH", gitPath)
+	checkouts := filepath.Join(gitPath, "checkouts")
+
+	stale := time.Now().Add(-2 * staleTmpAge)
+	for name, modTime := range map[string]time.Time{
+		"in-use-by-this-pipeline":  time.Now(),
+		"in-use-by-other-pipeline": time.Now(),
+		"unused":                   time.Now(),
+		".tmp-in-progress":         time.Now(),
+		".tmp-abandoned":           stale,
+	} {
+		dir := filepath.Join(checkouts, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	other := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-pipeline", Namespace: "other"},
+		Status:     v1alpha1.OperatorPipelineStatus{PipelinesRepoHash: "in-use-by-other-pipeline"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(other).WithStatusSubresource(other).Build()
+
+	// The status of the reconciled pipeline is not committed yet
+	pipeline := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci"},
+		Status:     v1alpha1.OperatorPipelineStatus{PipelinesRepoHash: "in-use-by-this-pipeline"},
+	}
+	if err := NewPipelineGitRepoReconciler(c, logr.Discard(), scheme, nil).pruneCheckouts(context.Background(), pipeline); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(checkouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, entry := range entries {
+		kept = append(kept, entry.Name())
+	}
+	want := []string{".tmp-in-progress", "in-use-by-other-pipeline", "in-use-by-this-pipeline"}
+	if !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+}
@@ -0,0 +1,337 @@
+package reconcilers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/blang/semver/v4"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultPipelinesRelease = "main"
+	pipelinesRemoteName     = git.DefaultRemoteName
+	maxReleaseHistory       = 10
+)
+
+// commitHashPattern matches full and abbreviated commit hashes, git accepts abbreviations down to 4 characters.
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// resolveRelease resolves the requested operator-pipelines release to a commit.
+// The release is looked up, in order, as an exact tag, a branch, a full or abbreviated commit hash,
+// and finally as a semver constraint (e.g. ~1.4) that selects the highest matching tag.
+func resolveRelease(r *git.Repository, release string) (*v1alpha1.ResolvedRelease, error) {
+	if len(release) == 0 {
+		release = defaultPipelinesRelease
+	}
+
+	resolved, err := resolveReleaseRef(r, release)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Release = release
+	return resolved, nil
+}
+
+func resolveReleaseRef(r *git.Repository, release string) (*v1alpha1.ResolvedRelease, error) {
+	if ref, err := r.Reference(plumbing.NewTagReferenceName(release), true); err == nil {
+		return resolvedTag(r, ref)
+	}
+
+	if ref, err := r.Reference(plumbing.NewRemoteReferenceName(pipelinesRemoteName, release), true); err == nil {
+		commit, err := r.CommitObject(ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+		return &v1alpha1.ResolvedRelease{
+			Type:   v1alpha1.ReleaseRefBranch,
+			Name:   release,
+			Commit: commit.Hash.String(),
+		}, nil
+	}
+
+	if commitHashPattern.MatchString(release) {
+		commit, err := findCommit(r, release)
+		if err != nil {
+			return nil, err
+		}
+		if commit != nil {
+			return &v1alpha1.ResolvedRelease{
+				Type:   v1alpha1.ReleaseRefCommit,
+				Name:   release,
+				Commit: commit.Hash.String(),
+			}, nil
+		}
+	}
+
+	constraint, err := parseReleaseConstraint(release)
+	if err != nil {
+		return nil, fmt.Errorf("requested release %s is not a tag, branch or commit in the repository", release)
+	}
+
+	ref, err := highestMatchingTag(r, constraint)
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("no tag in the repository satisfies the requested release %s", release)
+	}
+
+	return resolvedTag(r, ref)
+}
+
+// rolloutAllowed returns whether the pipeline may move from the installed release to the resolved one under its
+// update policy. Installing for the first time and changing the requested release or repository are explicit
+// requests, so they are always rolled out.
+func rolloutAllowed(pipeline *v1alpha1.OperatorPipeline, resolved *v1alpha1.ResolvedRelease) bool {
+	installed := pipeline.Status.ResolvedRelease
+	if installed == nil || len(pipeline.Status.PipelinesRepoHash) == 0 || installed.Commit == resolved.Commit ||
+		installed.Release != resolved.Release || installed.Repository != resolved.Repository {
+		return true
+	}
+
+	switch pipeline.Spec.UpdatePolicy {
+	case v1alpha1.UpdatePolicyManual:
+		return false
+	case v1alpha1.UpdatePolicyApproval:
+		return pipeline.Annotations[v1alpha1.ApprovedCommitAnnotation] == resolved.Commit
+	default:
+		return true
+	}
+}
+
+// rollbackTarget returns the release from the history of applied commits the pipeline requested to roll back to.
+func rollbackTarget(pipeline *v1alpha1.OperatorPipeline) (*v1alpha1.ResolvedRelease, error) {
+	commit := pipeline.Spec.RollbackToCommit
+
+	var target *v1alpha1.ResolvedRelease
+	for _, applied := range pipeline.Status.History {
+		if !strings.HasPrefix(applied.Commit, commit) {
+			continue
+		}
+		if target != nil && target.Commit != applied.Commit {
+			return nil, fmt.Errorf("abbreviated commit %s is ambiguous", commit)
+		}
+		release := applied.ResolvedRelease
+		target = &release
+	}
+
+	if target == nil {
+		return nil, fmt.Errorf("commit %s is not in the history of applied commits", commit)
+	}
+
+	return target, nil
+}
+
+// recordAppliedRelease adds the release the manifests were just applied from to the front of the history, unless
+// it is the most recent entry already. The history is bounded to the last maxReleaseHistory commits.
+func recordAppliedRelease(pipeline *v1alpha1.OperatorPipeline, appliedAt metav1.Time) {
+	resolved := pipeline.Status.ResolvedRelease
+	if resolved == nil || resolved.Commit != pipeline.Status.PipelinesRepoHash {
+		return
+	}
+
+	history := pipeline.Status.History
+	if len(history) > 0 && history[0].Commit == resolved.Commit {
+		return
+	}
+
+	history = append([]v1alpha1.AppliedRelease{{ResolvedRelease: *resolved, AppliedAt: appliedAt}}, history...)
+	if len(history) > maxReleaseHistory {
+		history = history[:maxReleaseHistory]
+	}
+	pipeline.Status.History = history
+}
+
+// availableUpdates returns the tags with a higher version than the installed release, lowest first. Releases that
+// are not a semver tag, such as branches, have no available updates. Pre-releases are ignored.
+func availableUpdates(installed *v1alpha1.ResolvedRelease, tags []string) []string {
+	if installed == nil || installed.Type != v1alpha1.ReleaseRefTag {
+		return nil
+	}
+	current, err := semver.ParseTolerant(installed.Name)
+	if err != nil {
+		return nil
+	}
+
+	versions := map[string]semver.Version{}
+	var newer []string
+	for _, tag := range tags {
+		version, err := semver.ParseTolerant(tag)
+		if err != nil || len(version.Pre) > 0 || !version.GT(current) {
+			continue
+		}
+		versions[tag] = version
+		newer = append(newer, tag)
+	}
+
+	sort.Slice(newer, func(i, j int) bool {
+		return versions[newer[i]].LT(versions[newer[j]])
+	})
+	return newer
+}
+
+// resolvedTag peels the tag reference to the commit it points to, lightweight and annotated tags are supported.
+func resolvedTag(r *git.Repository, ref *plumbing.Reference) (*v1alpha1.ResolvedRelease, error) {
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		tag, tagErr := r.TagObject(ref.Hash())
+		if tagErr != nil {
+			return nil, err
+		}
+		if commit, err = tag.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &v1alpha1.ResolvedRelease{
+		Type:   v1alpha1.ReleaseRefTag,
+		Name:   ref.Name().Short(),
+		Commit: commit.Hash.String(),
+	}, nil
+}
+
+// findCommit looks up a commit by its full or abbreviated hash. It returns nil when no commit matches,
+// and an error when an abbreviated hash is ambiguous.
+func findCommit(r *git.Repository, hash string) (*object.Commit, error) {
+	if len(hash) == 40 {
+		commit, err := r.CommitObject(plumbing.NewHash(hash))
+		if err == plumbing.ErrObjectNotFound {
+			return nil, nil
+		}
+		return commit, err
+	}
+
+	iter, err := r.CommitObjects()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var found *object.Commit
+	if err := iter.ForEach(func(commit *object.Commit) error {
+		if !strings.HasPrefix(commit.Hash.String(), hash) {
+			return nil
+		}
+		if found != nil {
+			return fmt.Errorf("abbreviated commit %s is ambiguous", hash)
+		}
+		found = commit
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// highestMatchingTag returns the tag with the highest semantic version satisfying the constraint.
+// Tags that are not semantic versions and pre-releases are ignored.
+func highestMatchingTag(r *git.Repository, constraint semver.Range) (*plumbing.Reference, error) {
+	iter, err := r.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var highest *plumbing.Reference
+	var highestVersion semver.Version
+	if err := iter.ForEach(func(ref *plumbing.Reference) error {
+		version, err := semver.ParseTolerant(ref.Name().Short())
+		if err != nil || len(version.Pre) > 0 || !constraint(version) {
+			return nil
+		}
+		if highest == nil || version.GT(highestVersion) {
+			highest, highestVersion = ref, version
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return highest, nil
+}
+
+// parseReleaseConstraint parses a semver constraint such as "~1.4", "^1.2", "1.4.x", "1.4" or ">=1.2 <2".
+// Constraints can be combined with "||". Tilde and caret ranges as well as partial versions are expanded
+// into the comparisons understood by the semver package.
+func parseReleaseConstraint(constraint string) (semver.Range, error) {
+	alternatives := strings.Split(strings.ReplaceAll(constraint, ",", " "), "||")
+	expanded := make([]string, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty constraint in %q", constraint)
+		}
+
+		comparisons := make([]string, 0, len(fields))
+		for _, field := range fields {
+			comparison, err := expandConstraint(field)
+			if err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, comparison)
+		}
+		expanded = append(expanded, strings.Join(comparisons, " "))
+	}
+
+	return semver.ParseRange(strings.Join(expanded, " || "))
+}
+
+func expandConstraint(field string) (string, error) {
+	switch {
+	case strings.HasPrefix(field, "~"):
+		return expandRange(strings.TrimPrefix(field, "~"), false)
+	case strings.HasPrefix(field, "^"):
+		return expandRange(strings.TrimPrefix(field, "^"), true)
+	case strings.ContainsAny(field[:1], "<>=!"):
+		op := strings.TrimRight(field, "v0123456789.")
+		version, err := semver.ParseTolerant(strings.TrimPrefix(field, op))
+		if err != nil {
+			return "", err
+		}
+		return op + version.String(), nil
+	case strings.ContainsAny(field, "xX*"):
+		return strings.TrimPrefix(field, "v"), nil
+	}
+
+	version := strings.TrimPrefix(field, "v")
+	if strings.Count(version, ".") < 2 {
+		return expandRange(version, false)
+	}
+	if _, err := semver.Parse(version); err != nil {
+		return "", err
+	}
+	return "=" + version, nil
+}
+
+// expandRange expands a tilde range, which allows patch level changes (or minor level changes when only
+// the major version is given), or a caret range, which allows changes that do not modify the left-most
+// non-zero component.
+func expandRange(partial string, caret bool) (string, error) {
+	partial = strings.TrimPrefix(partial, "v")
+	lower, err := semver.ParseTolerant(partial)
+	if err != nil {
+		return "", err
+	}
+
+	components := strings.Count(partial, ".") + 1
+	upper := semver.Version{Major: lower.Major + 1}
+	switch {
+	case caret && lower.Major == 0 && lower.Minor == 0 && components == 3:
+		upper = semver.Version{Patch: lower.Patch + 1}
+	case caret && lower.Major == 0 && components > 1:
+		upper = semver.Version{Minor: lower.Minor + 1}
+	case !caret && components > 1:
+		upper = semver.Version{Major: lower.Major, Minor: lower.Minor + 1}
+	}
+
+	return fmt.Sprintf(">=%s <%s", lower, upper), nil
+}
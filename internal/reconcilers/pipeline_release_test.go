@@ -0,0 +1,489 @@
+package reconcilers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/blang/semver/v4"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExpandRange(t *testing.T) {
+	tests := []struct {
+		partial string
+		caret   bool
+		want    string
+	}{
+		{"1", false, ">=1.0.0 <2.0.0"},
+		{"1.4", false, ">=1.4.0 <1.5.0"},
+		{"1.4.2", false, ">=1.4.2 <1.5.0"},
+		{"v1.4.2", false, ">=1.4.2 <1.5.0"},
+		{"0.2", false, ">=0.2.0 <0.3.0"},
+		{"1", true, ">=1.0.0 <2.0.0"},
+		{"1.2", true, ">=1.2.0 <2.0.0"},
+		{"1.2.3", true, ">=1.2.3 <2.0.0"},
+		{"0.2", true, ">=0.2.0 <0.3.0"},
+		{"0.2.3", true, ">=0.2.3 <0.3.0"},
+		{"0.0.3", true, ">=0.0.3 <0.0.4"},
+		{"0", true, ">=0.0.0 <1.0.0"},
+	}
+	for _, tt := range tests {
+		got, err := expandRange(tt.partial, tt.caret)
+		if err != nil {
+			t.Errorf("expandRange(%q, %v): %v", tt.partial, tt.caret, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandRange(%q, %v) = %q, want %q", tt.partial, tt.caret, got, tt.want)
+		}
+	}
+
+	if _, err := expandRange("x.y", false); err == nil {
+		t.Error("expandRange(\"x.y\") succeeded, want an error")
+	}
+}
+
+func TestParseReleaseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"~1.4", []string{"1.4.0", "1.4.9"}, []string{"1.3.9", "1.5.0"}},
+		{"1.4", []string{"1.4.0", "1.4.9"}, []string{"1.5.0"}},
+		{"v1.4", []string{"1.4.3"}, []string{"2.0.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.2.2", "0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"1.4.x", []string{"1.4.0", "1.4.7"}, []string{"1.5.0"}},
+		{">=1.2 <2", []string{"1.2.0", "1.9.0"}, []string{"1.1.0", "2.0.0"}},
+		{">=1.2, <2", []string{"1.5.0"}, []string{"2.1.0"}},
+		{"~1.4 || ~2.0", []string{"1.4.1", "2.0.3"}, []string{"1.5.0", "2.1.0"}},
+		{"1.4.2", []string{"1.4.2"}, []string{"1.4.3"}},
+		{"v1.4.2", []string{"1.4.2"}, []string{"1.4.1"}},
+		// Pinned pre-releases only match themselves, ranges match the pre-releases below their upper bound
+		{"1.5.0-rc.1", []string{"1.5.0-rc.1"}, []string{"1.5.0", "1.5.0-rc.2"}},
+		{"~1.4", []string{"1.5.0-rc.1"}, []string{"1.4.0-rc.1"}},
+	}
+	for _, tt := range tests {
+		constraint, err := parseReleaseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("parseReleaseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		for _, v := range tt.match {
+			if !constraint(semver.MustParse(v)) {
+				t.Errorf("%q does not match %s", tt.constraint, v)
+			}
+		}
+		for _, v := range tt.noMatch {
+			if constraint(semver.MustParse(v)) {
+				t.Errorf("%q matches %s", tt.constraint, v)
+			}
+		}
+	}
+
+	for _, constraint := range []string{"", "~1.4 ||", "main", "~x", ">=foo"} {
+		if _, err := parseReleaseConstraint(constraint); err == nil {
+			t.Errorf("parseReleaseConstraint(%q) succeeded, want an error", constraint)
+		}
+	}
+}
+
+// newReleaseRepository returns an in-memory repository laid out like a clone of operator-pipelines: one commit per
+// tag, lightweight and annotated tags, and remote branches.
+func newReleaseRepository(t *testing.T) (*git.Repository, map[string]plumbing.Hash) {
+	t.Helper()
+
+	r, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := map[string]plumbing.Hash{}
+	when := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, tag := range []string{"v1.4.0", "v1.4.2", "v1.5.0-rc.1", "v1.5.0", "v2.0.0", "latest"} {
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: when.Add(time.Duration(i) * time.Hour)}
+		hash, err := tree.Commit(tag, &git.CommitOptions{Author: signature, AllowEmptyCommits: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits[tag] = hash
+
+		if tag == "v1.5.0" {
+			_, err = r.CreateTag(tag, hash, &git.CreateTagOptions{Tagger: signature, Message: tag})
+		} else {
+			_, err = r.CreateTag(tag, hash, nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for branch, tag := range map[string]string{"main": "latest", "release-1.4": "v1.4.2"} {
+		ref := plumbing.NewHashReference(plumbing.NewRemoteReferenceName(pipelinesRemoteName, branch), commits[tag])
+		if err := r.Storer.SetReference(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return r, commits
+}
+
+func TestResolveReleaseRef(t *testing.T) {
+	r, commits := newReleaseRepository(t)
+
+	tests := []struct {
+		release string
+		want    v1alpha1.ResolvedRelease
+	}{
+		// Pinned tags resolve to themselves, even when a higher version matches
+		{"v1.4.0", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.4.0", Commit: commits["v1.4.0"].String()}},
+		{"1.4.0", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.4.0", Commit: commits["v1.4.0"].String()}},
+		{"v1.5.0-rc.1", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.5.0-rc.1", Commit: commits["v1.5.0-rc.1"].String()}},
+		// Annotated tags are peeled to their commit
+		{"v1.5.0", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.5.0", Commit: commits["v1.5.0"].String()}},
+		// Ranges select the highest matching tag, pre-releases are skipped
+		{"~1.4", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.4.2", Commit: commits["v1.4.2"].String()}},
+		{"^1.4", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.5.0", Commit: commits["v1.5.0"].String()}},
+		{">=1 <3", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v2.0.0", Commit: commits["v2.0.0"].String()}},
+		{"~1.4 || ~2.0", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v2.0.0", Commit: commits["v2.0.0"].String()}},
+		// Tags named like a branch win over the branch
+		{"latest", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "latest", Commit: commits["latest"].String()}},
+		{"main", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefBranch, Name: "main", Commit: commits["latest"].String()}},
+		{"release-1.4", v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefBranch, Name: "release-1.4", Commit: commits["v1.4.2"].String()}},
+		{commits["v2.0.0"].String(), v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefCommit, Name: commits["v2.0.0"].String(), Commit: commits["v2.0.0"].String()}},
+		{commits["v2.0.0"].String()[:12], v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefCommit, Name: commits["v2.0.0"].String()[:12], Commit: commits["v2.0.0"].String()}},
+	}
+	for _, tt := range tests {
+		got, err := resolveReleaseRef(r, tt.release)
+		if err != nil {
+			t.Errorf("resolveReleaseRef(%q): %v", tt.release, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("resolveReleaseRef(%q) = %+v, want %+v", tt.release, *got, tt.want)
+		}
+	}
+
+	// A pinned pre-release version is not a tag name, and ranges never select pre-releases
+	for _, release := range []string{"1.5.0-rc.1", "~3", "unknown-branch"} {
+		if got, err := resolveReleaseRef(r, release); err == nil {
+			t.Errorf("resolveReleaseRef(%q) = %+v, want an error", release, *got)
+		}
+	}
+}
+
+func TestRolloutAllowed(t *testing.T) {
+	const (
+		installedCommit = "1111111111111111111111111111111111111111"
+		newCommit       = "2222222222222222222222222222222222222222"
+		repository      = "https://github.com/redhat-openshift-ecosystem/operator-pipelines.git"
+	)
+	installed := &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefBranch, Name: "main", Commit: installedCommit, Repository: repository, Release: "main"}
+	update := &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefBranch, Name: "main", Commit: newCommit, Repository: repository, Release: "main"}
+
+	tests := []struct {
+		name        string
+		policy      v1alpha1.UpdatePolicy
+		installed   *v1alpha1.ResolvedRelease
+		appliedAt   string
+		approved    string
+		resolved    *v1alpha1.ResolvedRelease
+		wantRollout bool
+	}{
+		{
+			name:        "first install under Manual",
+			policy:      v1alpha1.UpdatePolicyManual,
+			resolved:    update,
+			wantRollout: true,
+		},
+		{
+			name:        "manifests never applied under Manual",
+			policy:      v1alpha1.UpdatePolicyManual,
+			installed:   installed,
+			resolved:    update,
+			wantRollout: true,
+		},
+		{
+			name:        "same commit under Manual",
+			policy:      v1alpha1.UpdatePolicyManual,
+			installed:   installed,
+			appliedAt:   installedCommit,
+			resolved:    installed,
+			wantRollout: true,
+		},
+		{
+			name:      "new commit under Manual",
+			policy:    v1alpha1.UpdatePolicyManual,
+			installed: installed,
+			appliedAt: installedCommit,
+			resolved:  update,
+		},
+		{
+			name:        "requested release changed under Manual",
+			policy:      v1alpha1.UpdatePolicyManual,
+			installed:   installed,
+			appliedAt:   installedCommit,
+			resolved:    &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.0.0", Commit: newCommit, Repository: repository, Release: "v1.0.0"},
+			wantRollout: true,
+		},
+		{
+			name:        "repository changed under Manual",
+			policy:      v1alpha1.UpdatePolicyManual,
+			installed:   installed,
+			appliedAt:   installedCommit,
+			resolved:    &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefBranch, Name: "main", Commit: newCommit, Repository: "https://git.example.com/operator-pipelines.git", Release: "main"},
+			wantRollout: true,
+		},
+		{
+			name:        "new commit under Automatic",
+			policy:      v1alpha1.UpdatePolicyAutomatic,
+			installed:   installed,
+			appliedAt:   installedCommit,
+			resolved:    update,
+			wantRollout: true,
+		},
+		{
+			name:        "new commit without a policy",
+			installed:   installed,
+			appliedAt:   installedCommit,
+			resolved:    update,
+			wantRollout: true,
+		},
+		{
+			name:      "new commit under Approval without approval",
+			policy:    v1alpha1.UpdatePolicyApproval,
+			installed: installed,
+			appliedAt: installedCommit,
+			resolved:  update,
+		},
+		{
+			name:      "new commit under Approval with another commit approved",
+			policy:    v1alpha1.UpdatePolicyApproval,
+			installed: installed,
+			appliedAt: installedCommit,
+			approved:  "3333333333333333333333333333333333333333",
+			resolved:  update,
+		},
+		{
+			name:        "new commit under Approval once approved",
+			policy:      v1alpha1.UpdatePolicyApproval,
+			installed:   installed,
+			appliedAt:   installedCommit,
+			approved:    newCommit,
+			resolved:    update,
+			wantRollout: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &v1alpha1.OperatorPipeline{
+				Spec:   v1alpha1.OperatorPipelineSpec{UpdatePolicy: tt.policy},
+				Status: v1alpha1.OperatorPipelineStatus{ResolvedRelease: tt.installed, PipelinesRepoHash: tt.appliedAt},
+			}
+			if len(tt.approved) > 0 {
+				pipeline.Annotations = map[string]string{v1alpha1.ApprovedCommitAnnotation: tt.approved}
+			}
+			if got := rolloutAllowed(pipeline, tt.resolved); got != tt.wantRollout {
+				t.Fatalf("rolloutAllowed = %v, want %v", got, tt.wantRollout)
+			}
+		})
+	}
+}
+
+func TestAvailableUpdates(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.10.0", "v1.2.0", "v1.2.0-rc.1", "v2.0.0", "v0.9.0", "latest", "1.3.0"}
+
+	tests := []struct {
+		name      string
+		installed *v1alpha1.ResolvedRelease
+		want      []string
+	}{
+		{
+			name: "nothing installed",
+		},
+		{
+			name:      "branch",
+			installed: &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefBranch, Name: "main"},
+		},
+		{
+			name:      "tag that is not a version",
+			installed: &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "latest"},
+		},
+		{
+			name:      "newer versions lowest first without pre-releases",
+			installed: &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v1.0.0"},
+			want:      []string{"v1.2.0", "1.3.0", "v1.10.0", "v2.0.0"},
+		},
+		{
+			name:      "highest version",
+			installed: &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: "v2.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := availableUpdates(tt.installed, tags)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("availableUpdates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollbackTarget(t *testing.T) {
+	applied := func(commit, name string) v1alpha1.AppliedRelease {
+		return v1alpha1.AppliedRelease{ResolvedRelease: v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefTag, Name: name, Commit: commit}}
+	}
+	history := []v1alpha1.AppliedRelease{
+		applied("abc1230000000000000000000000000000000000", "v1.2.0"),
+		applied("abc4560000000000000000000000000000000000", "v1.1.0"),
+		// Applied again after a rollback
+		applied("abc1230000000000000000000000000000000000", "v1.2.0"),
+		applied("def7890000000000000000000000000000000000", "v1.0.0"),
+	}
+
+	tests := []struct {
+		name    string
+		commit  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "full hash",
+			commit: "def7890000000000000000000000000000000000",
+			want:   "v1.0.0",
+		},
+		{
+			name:   "abbreviated hash",
+			commit: "abc456",
+			want:   "v1.1.0",
+		},
+		{
+			name:   "commit applied several times",
+			commit: "abc123",
+			want:   "v1.2.0",
+		},
+		{
+			name:    "ambiguous abbreviated hash",
+			commit:  "abc",
+			wantErr: "abbreviated commit abc is ambiguous",
+		},
+		{
+			name:    "commit not in the history",
+			commit:  "0123456",
+			wantErr: "commit 0123456 is not in the history of applied commits",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &v1alpha1.OperatorPipeline{
+				Spec:   v1alpha1.OperatorPipelineSpec{RollbackToCommit: tt.commit},
+				Status: v1alpha1.OperatorPipelineStatus{History: history},
+			}
+			target, err := rollbackTarget(pipeline)
+			if len(tt.wantErr) > 0 {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target.Name != tt.want {
+				t.Fatalf("rollback to %s, want %s", target.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAppliedRelease(t *testing.T) {
+	appliedAt := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	commit := func(i int) string {
+		return fmt.Sprintf("%040d", i)
+	}
+	history := func(commits ...int) []v1alpha1.AppliedRelease {
+		var releases []v1alpha1.AppliedRelease
+		for _, i := range commits {
+			releases = append(releases, v1alpha1.AppliedRelease{
+				ResolvedRelease: v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefCommit, Name: commit(i), Commit: commit(i)},
+				AppliedAt:       appliedAt,
+			})
+		}
+		return releases
+	}
+
+	tests := []struct {
+		name     string
+		resolved int
+		applied  int
+		history  []v1alpha1.AppliedRelease
+		want     []v1alpha1.AppliedRelease
+	}{
+		{
+			name: "nothing resolved",
+		},
+		{
+			name:     "resolved release not applied",
+			resolved: 2,
+			applied:  1,
+			history:  history(1),
+			want:     history(1),
+		},
+		{
+			name:     "first applied release",
+			resolved: 1,
+			applied:  1,
+			want:     history(1),
+		},
+		{
+			name:     "most recent entry already",
+			resolved: 2,
+			applied:  2,
+			history:  history(2, 1),
+			want:     history(2, 1),
+		},
+		{
+			name:     "rolled back to an older entry",
+			resolved: 1,
+			applied:  1,
+			history:  history(2, 1),
+			want:     history(1, 2, 1),
+		},
+		{
+			name:     "history capped to the last 10 commits",
+			resolved: 11,
+			applied:  11,
+			history:  history(10, 9, 8, 7, 6, 5, 4, 3, 2, 1),
+			want:     history(11, 10, 9, 8, 7, 6, 5, 4, 3, 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &v1alpha1.OperatorPipeline{Status: v1alpha1.OperatorPipelineStatus{History: tt.history}}
+			if tt.resolved > 0 {
+				pipeline.Status.ResolvedRelease = &history(tt.resolved)[0].ResolvedRelease
+				pipeline.Status.PipelinesRepoHash = commit(tt.applied)
+			}
+			recordAppliedRelease(pipeline, appliedAt)
+			if !reflect.DeepEqual(pipeline.Status.History, tt.want) {
+				t.Fatalf("history %+v, want %+v", pipeline.Status.History, tt.want)
+			}
+		})
+	}
+}
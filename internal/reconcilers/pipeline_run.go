@@ -0,0 +1,155 @@
+package reconcilers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CertificationRunLabel is set on the PipelineRuns of a CertificationRun, with its name
+	CertificationRunLabel = "certification.redhat.com/certification-run"
+	// OCPVersionLabel is set on the PipelineRuns of a CertificationRun, with the OpenShift version it targets
+	OCPVersionLabel = "certification.redhat.com/ocp-version"
+
+	pipelineWorkspace            = "pipeline"
+	kubeconfigWorkspace          = "kubeconfig"
+	sshDirWorkspace              = "ssh-dir"
+	registryCredentialsWorkspace = "registry-credentials"
+)
+
+// NewPipelineRun returns the PipelineRun of a CertificationRun. The params and workspaces the operator knows about
+// are only passed when the Pipeline declares them, so the same run can target any pipeline of the release. The
+// secrets come from the OperatorPipeline and the claims of the workspaces from its workspace settings.
+func NewPipelineRun(operatorPipeline *v1alpha1.OperatorPipeline, pipeline *tekton.Pipeline, run *v1alpha1.CertificationRun) *tekton.PipelineRun {
+	values := pipelineRunValues(operatorPipeline)
+	values["git_repo_url"] = run.Spec.GitRepoURL
+	values["git_revision"] = run.Spec.GitRevision
+	values["bundle_path"] = run.Spec.BundlePath
+	values["ocp_version"] = run.Spec.OCPVersion
+	for name, value := range run.Spec.Params {
+		values[name] = value
+	}
+
+	labels := map[string]string{CertificationRunLabel: run.Name}
+	if len(run.Spec.OCPVersion) > 0 {
+		labels[OCPVersionLabel] = run.Spec.OCPVersion
+	}
+
+	return &tekton.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: run.Name + "-",
+			Namespace:    run.Namespace,
+			Labels:       labels,
+		},
+		Spec: pipelineRunSpec(operatorPipeline, pipeline, values),
+	}
+}
+
+// pipelineRunValues returns the values of the params naming the secrets of the OperatorPipeline.
+func pipelineRunValues(operatorPipeline *v1alpha1.OperatorPipeline) map[string]string {
+	return map[string]string{
+		"kubeconfig_secret_name":    overrideSecretFromSpec(defaultKubeconfigSecretName, operatorPipeline.Spec.KubeconfigSecretName),
+		"kubeconfig_secret_key":     defaultKubeconfigSecretKeyName,
+		"github_token_secret_name":  overrideSecretFromSpec(defaultGithubAPISecretName, operatorPipeline.Spec.GitHubSecretName),
+		"github_token_secret_key":   defaultGithubAPISecretKeyName,
+		"pyxis_api_key_secret_name": overrideSecretFromSpec(defaultPyxisAPISecretName, operatorPipeline.Spec.PyxisSecretName),
+		"pyxis_api_key_secret_key":  defaultPyxisAPISecretKeyName,
+	}
+}
+
+// pipelineRunSpec returns the spec of a PipelineRun of the pipeline, passing the values of the params it declares
+// and binding the workspaces it declares.
+func pipelineRunSpec(operatorPipeline *v1alpha1.OperatorPipeline, pipeline *tekton.Pipeline, values map[string]string) tekton.PipelineRunSpec {
+	var params tekton.Params
+	for _, spec := range pipeline.Spec.Params {
+		value, ok := values[spec.Name]
+		if !ok || len(value) == 0 {
+			continue
+		}
+		params = append(params, tekton.Param{Name: spec.Name, Value: *tekton.NewStructuredValues(value)})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+
+	var workspaces []tekton.WorkspaceBinding
+	for _, declaration := range pipeline.Spec.Workspaces {
+		if binding := workspaceBinding(operatorPipeline, declaration); binding != nil {
+			workspaces = append(workspaces, *binding)
+		}
+	}
+
+	return tekton.PipelineRunSpec{
+		PipelineRef:     &tekton.PipelineRef{Name: pipeline.Name},
+		Params:          params,
+		TaskRunTemplate: tekton.PipelineTaskRunTemplate{ServiceAccountName: serviceAccountName(operatorPipeline)},
+		Workspaces:      workspaces,
+	}
+}
+
+// workspaceBinding binds the kubeconfig, ssh-dir and registry-credentials workspaces to the secrets of the
+// OperatorPipeline, and the other required workspaces to a claim. Optional workspaces without a secret are left unbound.
+func workspaceBinding(operatorPipeline *v1alpha1.OperatorPipeline, declaration tekton.PipelineWorkspaceDeclaration) *tekton.WorkspaceBinding {
+	secrets := map[string]string{
+		kubeconfigWorkspace:          overrideSecretFromSpec(defaultKubeconfigSecretName, operatorPipeline.Spec.KubeconfigSecretName),
+		sshDirWorkspace:              operatorPipeline.Spec.GithubSSHSecretName,
+		registryCredentialsWorkspace: operatorPipeline.Spec.DockerRegistrySecretName,
+	}
+	if secretName, ok := secrets[declaration.Name]; ok && len(secretName) > 0 {
+		return &tekton.WorkspaceBinding{
+			Name:   declaration.Name,
+			Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+		}
+	}
+
+	if declaration.Optional {
+		return nil
+	}
+	return &tekton.WorkspaceBinding{
+		Name: declaration.Name,
+		VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
+			Spec: workspaceClaimSpec(operatorPipeline.Spec.Workspaces),
+		},
+	}
+}
+
+// PipelineRunTaskRuns returns the TaskRuns of a PipelineRun, in the order of its child references. TaskRuns that
+// were already deleted are left out.
+func PipelineRunTaskRuns(ctx context.Context, c client.Client, pr *tekton.PipelineRun) ([]tekton.TaskRun, error) {
+	var taskRuns []tekton.TaskRun
+	for _, child := range pr.Status.ChildReferences {
+		if child.Kind != "TaskRun" {
+			continue
+		}
+		tr := tekton.TaskRun{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: child.Name}, &tr)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		taskRuns = append(taskRuns, tr)
+	}
+	return taskRuns, nil
+}
+
+// ResultValue returns string results as they are and the other ones in JSON.
+func ResultValue(value tekton.ResultValue) (string, error) {
+	if value.Type == tekton.ParamTypeString {
+		return value.StringVal, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
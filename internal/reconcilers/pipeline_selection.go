@@ -0,0 +1,121 @@
+package reconcilers
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const pipelinesReadyCondition = "PipelinesReady"
+
+// selectedPipeline is a pipeline of the release selected in the spec, with its alias applied.
+type selectedPipeline struct {
+	selection v1alpha1.PipelineSelection
+	fileName  string
+	objs      []*unstructured.Unstructured
+	// problem tells why the selection could not be resolved
+	problem string
+}
+
+// pipelineName returns the name the Pipeline of the selection is installed as.
+func (s selectedPipeline) pipelineName() string {
+	for _, obj := range s.objs {
+		if obj.GroupVersionKind() == tekton.SchemeGroupVersion.WithKind("Pipeline") {
+			return obj.GetName()
+		}
+	}
+	return ""
+}
+
+// selectPipelines resolves the pipelines selected in the spec against the pipelines directory of the release.
+// A selection names a file, with or without its extension, or else a Pipeline of any of the files. Selections
+// that cannot be resolved are returned with the problem, so every problem is reported at once.
+func selectPipelines(manifests fs.FS, mapper meta.RESTMapper, vars map[string]interface{}, pipeline *v1alpha1.OperatorPipeline) ([]selectedPipeline, error) {
+	entries, err := fs.ReadDir(manifests, pipelineManifestsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, entry.Name())
+		}
+	}
+
+	loaded := map[string][]*unstructured.Unstructured{}
+	loadErrors := map[string]error{}
+	load := func(file string) ([]*unstructured.Unstructured, error) {
+		if objs, ok := loaded[file]; ok {
+			return objs, loadErrors[file]
+		}
+		objs, err := loadManifests(manifests, path.Join(pipelineManifestsPath, file), mapper, vars, pipeline.Namespace)
+		loaded[file], loadErrors[file] = objs, err
+		return objs, err
+	}
+
+	var selected []selectedPipeline
+	for _, selection := range pipeline.Spec.Pipelines {
+		s := selectedPipeline{selection: selection}
+
+		for _, file := range files {
+			if file == selection.Name || file == selection.Name+".yml" || file == selection.Name+".yaml" {
+				s.fileName = path.Join(pipelineManifestsPath, file)
+				objs, err := load(file)
+				if err != nil {
+					s.problem = err.Error()
+				}
+				s.objs = deepCopyObjects(objs)
+				break
+			}
+		}
+
+		if len(s.fileName) == 0 {
+			for _, file := range files {
+				// Files that fail to load are reported when they are selected by name
+				objs, _ := load(file)
+				for _, obj := range objs {
+					if obj.GroupVersionKind() == tekton.SchemeGroupVersion.WithKind("Pipeline") && obj.GetName() == selection.Name {
+						s.fileName = path.Join(pipelineManifestsPath, file)
+						s.objs = []*unstructured.Unstructured{obj.DeepCopy()}
+					}
+				}
+			}
+		}
+
+		switch {
+		case len(s.fileName) == 0:
+			s.problem = fmt.Sprintf("pipeline %s: no file or Pipeline of the release has this name", selection.Name)
+		case len(s.problem) == 0 && len(selection.Alias) > 0:
+			var pipelines []*unstructured.Unstructured
+			for _, obj := range s.objs {
+				if obj.GroupVersionKind() == tekton.SchemeGroupVersion.WithKind("Pipeline") {
+					pipelines = append(pipelines, obj)
+				}
+			}
+			if len(pipelines) != 1 {
+				s.problem = fmt.Sprintf("%s: an alias needs a single Pipeline, found %d", s.fileName, len(pipelines))
+				break
+			}
+			pipelines[0].SetName(selection.Alias)
+		}
+
+		selected = append(selected, s)
+	}
+
+	return selected, nil
+}
+
+func deepCopyObjects(objs []*unstructured.Unstructured) []*unstructured.Unstructured {
+	copies := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		copies = append(copies, obj.DeepCopy())
+	}
+	return copies
+}
@@ -0,0 +1,232 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/metrics"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// DefaultFetchInterval is the minimum time between two fetches of the same operator-pipelines release.
+const DefaultFetchInterval = 5 * time.Minute
+
+// RepositoryCache keeps the operator-pipelines repositories open across reconciles and limits how often they are
+// fetched. It is shared by every OperatorPipeline, so pipelines requesting the same remote and release only cause
+// a single fetch per interval.
+type RepositoryCache struct {
+	fetchInterval time.Duration
+
+	mu    sync.Mutex
+	repos map[string]*cachedRepository
+}
+
+type cachedRepository struct {
+	mu   sync.Mutex
+	repo *git.Repository
+	// lastFetch records when each release was last brought up to date with the remote
+	lastFetch map[string]time.Time
+	// remoteTags are the tags the remote had when it was last listed
+	remoteTags []string
+}
+
+// NewRepositoryCache returns a cache fetching each release at most once per fetchInterval.
+func NewRepositoryCache(fetchInterval time.Duration) *RepositoryCache {
+	return &RepositoryCache{
+		fetchInterval: fetchInterval,
+		repos:         map[string]*cachedRepository{},
+	}
+}
+
+func (c *RepositoryCache) entry(targetPath string) *cachedRepository {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.repos[targetPath]
+	if !ok {
+		entry = &cachedRepository{lastFetch: map[string]time.Time{}}
+		c.repos[targetPath] = entry
+	}
+	return entry
+}
+
+// cloneOrPullRepo returns the repository at targetPath, cloning it from remote if needed. The remote is only
+// contacted when the release has not been fetched within the fetch interval, so on the hot path this is a lookup.
+func (c *RepositoryCache) cloneOrPullRepo(ctx context.Context, targetPath, remote string, auth transport.AuthMethod, release string) (*git.Repository, error) {
+	if len(release) == 0 {
+		release = defaultPipelinesRelease
+	}
+
+	entry := c.entry(targetPath)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	label := remoteLabel(remote)
+	if last, ok := entry.lastFetch[release]; ok && entry.repo != nil && time.Since(last) < c.fetchInterval {
+		metrics.GitFetchTotal.WithLabelValues(label, metrics.FetchResultCached).Inc()
+		return entry.repo, nil
+	}
+
+	start := time.Now()
+	result, err := entry.update(ctx, targetPath, remote, auth, release)
+	if err != nil {
+		result = metrics.FetchResultError
+	}
+	metrics.GitFetchDuration.WithLabelValues(label, result).Observe(time.Since(start).Seconds())
+	metrics.GitFetchTotal.WithLabelValues(label, result).Inc()
+	if err != nil {
+		return nil, err
+	}
+
+	entry.lastFetch[release] = time.Now()
+	return entry.repo, nil
+}
+
+// update opens or clones the repository and fetches what is needed to resolve the release.
+func (e *cachedRepository) update(ctx context.Context, targetPath, remote string, auth transport.AuthMethod, release string) (string, error) {
+	if e.repo == nil {
+		// Try to clone first. The clone is bare since it only serves as the object store for the checkouts.
+		r, err := git.PlainCloneContext(ctx, targetPath, true, &git.CloneOptions{
+			URL:  remote,
+			Auth: auth,
+		})
+		switch {
+		case err == nil:
+			e.repo = r
+			return metrics.FetchResultUpdated, nil
+		case err != git.ErrRepositoryAlreadyExists:
+			// Don't leave a half cloned repository behind, it would be mistaken for a valid one on the next attempt
+			_ = os.RemoveAll(targetPath)
+			return "", err
+		}
+
+		// The directory is already there, so let's just update to latest
+		if e.repo, err = git.PlainOpen(targetPath); err != nil {
+			return "", err
+		}
+	}
+
+	opts, err := e.fetchOptions(ctx, auth, release)
+	if err != nil {
+		return "", err
+	}
+	if opts == nil {
+		return metrics.FetchResultUpToDate, nil
+	}
+
+	if err := e.repo.FetchContext(ctx, opts); err != nil {
+		if err == git.NoErrAlreadyUpToDate {
+			return metrics.FetchResultUpToDate, nil
+		}
+		return "", err
+	}
+
+	return metrics.FetchResultUpdated, nil
+}
+
+// fetchOptions narrows the fetch down to the single ref the release names when possible. It returns nil when
+// nothing needs to be fetched: the release is a commit already in the store, or the ref it names still points
+// to the commit we have. Everything else, like semver constraints and abbreviated hashes, needs a full fetch.
+func (e *cachedRepository) fetchOptions(ctx context.Context, auth transport.AuthMethod, release string) (*git.FetchOptions, error) {
+	origin, err := e.repo.Remote(pipelinesRemoteName)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := origin.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return nil, err
+	}
+
+	// The tags are kept so the available updates can be reported without fetching every tag
+	e.remoteTags = nil
+	remoteRefs := map[plumbing.ReferenceName]plumbing.Hash{}
+	for _, ref := range refs {
+		remoteRefs[ref.Name()] = ref.Hash()
+		if ref.Name().IsTag() {
+			e.remoteTags = append(e.remoteTags, ref.Name().Short())
+		}
+	}
+
+	if len(release) == 40 && commitHashPattern.MatchString(release) {
+		if _, err := e.repo.CommitObject(plumbing.NewHash(release)); err == nil {
+			return nil, nil
+		}
+	}
+
+	// Tags are looked up before branches, following the order releases are resolved in
+	candidates := []struct{ remoteName, localName plumbing.ReferenceName }{
+		{plumbing.NewTagReferenceName(release), plumbing.NewTagReferenceName(release)},
+		{plumbing.NewBranchReferenceName(release), plumbing.NewRemoteReferenceName(pipelinesRemoteName, release)},
+	}
+	for _, candidate := range candidates {
+		hash, ok := remoteRefs[candidate.remoteName]
+		if !ok {
+			continue
+		}
+
+		if local, err := e.repo.Reference(candidate.localName, false); err == nil && local.Hash() == hash {
+			return nil, nil
+		}
+
+		return &git.FetchOptions{
+			RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", candidate.remoteName, candidate.localName))},
+			Tags:     git.NoTags,
+			Auth:     auth,
+		}, nil
+	}
+
+	// Pruning removes branches deleted upstream, so a stale branch is never mistaken for the requested release.
+	return &git.FetchOptions{Tags: git.AllTags, Prune: true, Auth: auth}, nil
+}
+
+// tags returns the tags of the remote at targetPath, as last listed or fetched.
+func (c *RepositoryCache) tags(targetPath string) ([]string, error) {
+	entry := c.entry(targetPath)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.repo == nil {
+		return nil, nil
+	}
+
+	seen := map[string]bool{}
+	for _, tag := range entry.remoteTags {
+		seen[tag] = true
+	}
+
+	iter, err := entry.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	if err := iter.ForEach(func(ref *plumbing.Reference) error {
+		seen[ref.Name().Short()] = true
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// remoteLabel returns the remote without any credentials embedded in the URL, for use as a metric label.
+func remoteLabel(remote string) string {
+	ep, err := transport.NewEndpoint(remote)
+	if err != nil {
+		return remote
+	}
+	ep.User = ""
+	ep.Password = ""
+	return ep.String()
+}
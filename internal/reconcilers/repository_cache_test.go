@@ -0,0 +1,157 @@
+package reconcilers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/metrics"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// testRemote is a bare repository served through the file transport, with a work tree to commit from.
+type testRemote struct {
+	url  string
+	work *git.Repository
+	dir  string
+}
+
+func newTestRemote(t *testing.T) *testRemote {
+	t.Helper()
+
+	bareDir := filepath.Join(t.TempDir(), "remote.git")
+	bare, err := git.PlainInit(bareDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))
+	if err := bare.Storer.SetReference(head); err != nil {
+		t.Fatal(err)
+	}
+
+	workDir := t.TempDir()
+	work, err := git.PlainInit(workDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := work.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{bareDir}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testRemote{url: "file://" + bareDir, work: work, dir: workDir}
+}
+
+// commit commits a change to the main branch and pushes it to the remote.
+func (r *testRemote) commit(t *testing.T, message string) plumbing.Hash {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(r.dir, "README.md"), []byte(message), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := r.work.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := tree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The work tree may have been initialized on master, the remote branch is always main
+	head, err := r.work.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refSpec := config.RefSpec(head.Name().String() + ":refs/heads/main")
+	if err := r.work.Push(&git.PushOptions{RefSpecs: []config.RefSpec{refSpec}, Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func fetchCount(t *testing.T, remote, result string) float64 {
+	t.Helper()
+
+	m := &dto.Metric{}
+	if err := metrics.GitFetchTotal.WithLabelValues(remote, result).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func remoteBranch(t *testing.T, r *git.Repository, branch string) plumbing.Hash {
+	t.Helper()
+
+	ref, err := r.Reference(plumbing.NewRemoteReferenceName(pipelinesRemoteName, branch), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref.Hash()
+}
+
+func TestRepositoryCacheFetchInterval(t *testing.T) {
+	ctx := context.Background()
+	remote := newTestRemote(t)
+	first := remote.commit(t, "first")
+
+	cache := NewRepositoryCache(time.Hour)
+	target := filepath.Join(t.TempDir(), "operator-pipelines")
+	label := remoteLabel(remote.url)
+
+	r, err := cache.cloneOrPullRepo(ctx, target, remote.url, nil, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := remoteBranch(t, r, "main"); got != first {
+		t.Fatalf("clone: main is %s, want %s", got, first)
+	}
+
+	second := remote.commit(t, "second")
+
+	// Within the interval the remote is not contacted, so the new commit is not seen
+	cached := fetchCount(t, label, metrics.FetchResultCached)
+	if r, err = cache.cloneOrPullRepo(ctx, target, remote.url, nil, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := remoteBranch(t, r, "main"); got != first {
+		t.Fatalf("within interval: main is %s, want %s", got, first)
+	}
+	if got := fetchCount(t, label, metrics.FetchResultCached); got != cached+1 {
+		t.Fatalf("within interval: %v cached lookups, want %v", got, cached+1)
+	}
+
+	// Once the interval elapsed the release is fetched again
+	cache.entry(target).lastFetch["main"] = time.Now().Add(-2 * time.Hour)
+	updated := fetchCount(t, label, metrics.FetchResultUpdated)
+	if r, err = cache.cloneOrPullRepo(ctx, target, remote.url, nil, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := remoteBranch(t, r, "main"); got != second {
+		t.Fatalf("after interval: main is %s, want %s", got, second)
+	}
+	if got := fetchCount(t, label, metrics.FetchResultUpdated); got != updated+1 {
+		t.Fatalf("after interval: %v fetches, want %v", got, updated+1)
+	}
+
+	// Nothing new upstream, the fetch is skipped after listing the remote
+	cache.entry(target).lastFetch["main"] = time.Now().Add(-2 * time.Hour)
+	upToDate := fetchCount(t, label, metrics.FetchResultUpToDate)
+	if _, err = cache.cloneOrPullRepo(ctx, target, remote.url, nil, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fetchCount(t, label, metrics.FetchResultUpToDate); got != upToDate+1 {
+		t.Fatalf("up to date: %v lookups, want %v", got, upToDate+1)
+	}
+}
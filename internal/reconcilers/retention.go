@@ -0,0 +1,212 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/metrics"
+
+	"github.com/go-logr/logr"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reportWaitRequeue is how long retention waits for the report of a PipelineRun it would prune
+const reportWaitRequeue = 30 * time.Second
+
+type RetentionReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// requeueAfter is when the next kept PipelineRun gets older than the maximum age
+	requeueAfter time.Duration
+}
+
+func NewRetentionReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *RetentionReconciler {
+	return &RetentionReconciler{
+		Client: client,
+		Log:    log,
+		Scheme: scheme,
+	}
+}
+
+// Reconcile prunes the completed PipelineRuns of the Pipelines installed by the OperatorPipeline that the retention
+// policy does not keep. PipelineRuns are deleted in the background, so their TaskRuns and the claims of their
+// workspaces are garbage collected with them. A PipelineRun is only pruned once its report ConfigMap is written, and
+// in ReportOnly mode nothing is pruned, the PipelineRuns that would be are counted in the status. With a maximum age,
+// RequeueAfter tells when the next kept PipelineRun expires.
+func (r *RetentionReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	r.requeueAfter = 0
+	policy := pipeline.Spec.Retention
+	if policy == nil {
+		pipeline.Status.Retention = nil
+		return false, nil
+	}
+	if pipeline.Status.Retention == nil {
+		pipeline.Status.Retention = &v1alpha1.RetentionStatus{}
+	}
+
+	managed, err := r.managedPipelines(ctx, pipeline)
+	if err != nil {
+		return true, err
+	}
+
+	runs := &tekton.PipelineRunList{}
+	if err := r.List(ctx, runs, client.InNamespace(pipeline.Namespace)); err != nil {
+		return true, err
+	}
+
+	// The TaskRuns the report is written from go away with the PipelineRun
+	reports := &corev1.ConfigMapList{}
+	if err := r.List(ctx, reports, client.InNamespace(pipeline.Namespace), client.HasLabels{CertificationReportLabel}); err != nil {
+		return true, err
+	}
+	reported := map[string]bool{}
+	for _, cm := range reports.Items {
+		reported[cm.Labels[CertificationReportLabel]] = true
+	}
+
+	var succeeded, failed []*tekton.PipelineRun
+	for i := range runs.Items {
+		pr := &runs.Items[i]
+		if pr.Spec.PipelineRef == nil || !managed[pr.Spec.PipelineRef.Name] || !pr.IsDone() || pr.DeletionTimestamp != nil {
+			continue
+		}
+		if pr.Status.GetCondition(apis.ConditionSucceeded).IsTrue() {
+			succeeded = append(succeeded, pr)
+		} else {
+			failed = append(failed, pr)
+		}
+	}
+
+	now := time.Now()
+	pipeline.Status.Retention.PruneCandidates = 0
+	for _, group := range []struct {
+		runs   []*tekton.PipelineRun
+		keep   *int32
+		result string
+		pruned *int64
+	}{
+		{succeeded, policy.KeepSuccessful, metrics.RunResultSucceeded, &pipeline.Status.Retention.PrunedSucceeded},
+		{failed, policy.KeepFailed, metrics.RunResultFailed, &pipeline.Status.Retention.PrunedFailed},
+	} {
+		candidates := pruneCandidates(group.runs, group.keep, policy.MaxAge, now)
+		r.requeueAt(nextExpiry(group.runs, candidates, policy.MaxAge, now))
+		for _, pr := range candidates {
+			if !reported[pr.Name] {
+				r.Log.Info(fmt.Sprintf("waiting for the report of PipelineRun %s before pruning it", pr.Name))
+				r.requeueAt(reportWaitRequeue)
+				continue
+			}
+			if reportOnly(pipeline) {
+				pipeline.Status.Retention.PruneCandidates++
+				continue
+			}
+
+			err := r.Delete(ctx, pr, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				r.Log.Error(err, fmt.Sprintf("could not prune PipelineRun %s", pr.Name))
+				return true, err
+			}
+
+			r.Log.Info(fmt.Sprintf("pruned %s PipelineRun %s", group.result, pr.Name))
+			*group.pruned++
+			pipeline.Status.Retention.LastPruneTime = &metav1.Time{Time: now}
+			metrics.PipelineRunsPrunedTotal.WithLabelValues(pipeline.Namespace, pipeline.Name, group.result).Inc()
+		}
+	}
+
+	return false, nil
+}
+
+// RequeueAfter returns when the next PipelineRun kept by the last reconcile gets older than the maximum age, zero
+// when there is no maximum age or no PipelineRun is kept.
+func (r *RetentionReconciler) RequeueAfter() time.Duration {
+	return r.requeueAfter
+}
+
+// requeueAt makes RequeueAfter return after when it comes before the current one, after is ignored when zero.
+func (r *RetentionReconciler) requeueAt(after time.Duration) {
+	if after > 0 && (r.requeueAfter == 0 || after < r.requeueAfter) {
+		r.requeueAfter = after
+	}
+}
+
+// managedPipelines returns the names of the Pipelines installed by the OperatorPipeline.
+func (r *RetentionReconciler) managedPipelines(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (map[string]bool, error) {
+	pipelines := &tekton.PipelineList{}
+	if err := r.List(ctx, pipelines, client.InNamespace(pipeline.Namespace)); err != nil {
+		return nil, err
+	}
+
+	managed := map[string]bool{}
+	for i := range pipelines.Items {
+		if metav1.IsControlledBy(&pipelines.Items[i], pipeline) {
+			managed[pipelines.Items[i].Name] = true
+		}
+	}
+	return managed, nil
+}
+
+// pruneCandidates returns the runs beyond the most recent ones to keep, and the ones completed longer ago than the
+// maximum age.
+func pruneCandidates(runs []*tekton.PipelineRun, keep *int32, maxAge *metav1.Duration, now time.Time) []*tekton.PipelineRun {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return completionTime(runs[j]).Before(completionTime(runs[i]))
+	})
+
+	var candidates []*tekton.PipelineRun
+	for i, pr := range runs {
+		beyondKept := keep != nil && i >= int(*keep)
+		tooOld := maxAge != nil && now.Sub(completionTime(pr)) > maxAge.Duration
+		if beyondKept || tooOld {
+			candidates = append(candidates, pr)
+		}
+	}
+	return candidates
+}
+
+// nextExpiry returns how long until the first of the runs that are not pruned gets older than the maximum age, zero
+// when none will.
+func nextExpiry(runs, pruned []*tekton.PipelineRun, maxAge *metav1.Duration, now time.Time) time.Duration {
+	if maxAge == nil {
+		return 0
+	}
+	prunedRuns := map[*tekton.PipelineRun]bool{}
+	for _, pr := range pruned {
+		prunedRuns[pr] = true
+	}
+
+	var next time.Duration
+	for _, pr := range runs {
+		if prunedRuns[pr] {
+			continue
+		}
+		// A run is pruned once it is strictly older than the maximum age
+		after := completionTime(pr).Add(maxAge.Duration).Sub(now) + time.Second
+		if next == 0 || after < next {
+			next = after
+		}
+	}
+	return next
+}
+
+// completionTime returns when the run completed, or when it was created for runs that never started.
+func completionTime(pr *tekton.PipelineRun) time.Time {
+	if pr.Status.CompletionTime != nil {
+		return pr.Status.CompletionTime.Time
+	}
+	return pr.CreationTimestamp.Time
+}
@@ -0,0 +1,239 @@
+package reconcilers
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testPipelineRun(name string, succeeded bool, completed time.Time) *tekton.PipelineRun {
+	pr := &tekton.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       tekton.PipelineRunSpec{PipelineRef: &tekton.PipelineRef{Name: "operator-ci-pipeline"}},
+	}
+	status := corev1.ConditionFalse
+	if succeeded {
+		status = corev1.ConditionTrue
+	}
+	pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: status})
+	pr.Status.CompletionTime = &metav1.Time{Time: completed}
+	return pr
+}
+
+func TestRetentionReconciler(t *testing.T) {
+	now := time.Now()
+	pipeline := &v1alpha1.OperatorPipeline{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "OperatorPipeline"},
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: testNamespace, UID: "uid"},
+	}
+	installed := &tekton.Pipeline{ObjectMeta: metav1.ObjectMeta{
+		Name:      "operator-ci-pipeline",
+		Namespace: testNamespace,
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "OperatorPipeline",
+			Name:       pipeline.Name,
+			UID:        pipeline.UID,
+			Controller: ptr.To(true),
+		}},
+	}}
+	runs := []client.Object{
+		testPipelineRun("succeeded-new", true, now.Add(-1*time.Hour)),
+		testPipelineRun("succeeded-old", true, now.Add(-5*time.Hour)),
+		testPipelineRun("succeeded-expired", true, now.Add(-30*time.Hour)),
+		testPipelineRun("failed-new", false, now.Add(-2*time.Hour)),
+		testPipelineRun("failed-old", false, now.Add(-3*time.Hour)),
+	}
+
+	tests := []struct {
+		name           string
+		policy         *v1alpha1.RetentionPolicy
+		mode           v1alpha1.ReconcileMode
+		unreported     []string
+		wantKept       []string
+		wantAfter      time.Duration
+		wantCandidates int64
+	}{
+		{
+			name:     "counts only",
+			policy:   &v1alpha1.RetentionPolicy{KeepSuccessful: ptr.To[int32](1), KeepFailed: ptr.To[int32](1)},
+			wantKept: []string{"failed-new", "succeeded-new"},
+		},
+		{
+			name:      "maximum age requeues at the first expiry",
+			policy:    &v1alpha1.RetentionPolicy{MaxAge: &metav1.Duration{Duration: 24 * time.Hour}},
+			wantKept:  []string{"failed-new", "failed-old", "succeeded-new", "succeeded-old"},
+			wantAfter: 19 * time.Hour,
+		},
+		{
+			name: "maximum age ignores the runs pruned by count",
+			policy: &v1alpha1.RetentionPolicy{
+				KeepSuccessful: ptr.To[int32](1),
+				KeepFailed:     ptr.To[int32](1),
+				MaxAge:         &metav1.Duration{Duration: 24 * time.Hour},
+			},
+			wantKept:  []string{"failed-new", "succeeded-new"},
+			wantAfter: 22 * time.Hour,
+		},
+		{
+			name:       "runs are kept until they are reported",
+			policy:     &v1alpha1.RetentionPolicy{KeepSuccessful: ptr.To[int32](1), KeepFailed: ptr.To[int32](1)},
+			unreported: []string{"succeeded-old"},
+			wantKept:   []string{"failed-new", "succeeded-new", "succeeded-old"},
+			wantAfter:  reportWaitRequeue,
+		},
+		{
+			name:           "ReportOnly counts the runs it would prune",
+			policy:         &v1alpha1.RetentionPolicy{KeepSuccessful: ptr.To[int32](1), KeepFailed: ptr.To[int32](1)},
+			mode:           v1alpha1.ReconcileModeReportOnly,
+			wantKept:       []string{"failed-new", "failed-old", "succeeded-expired", "succeeded-new", "succeeded-old"},
+			wantCandidates: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := testScheme(t)
+			if err := tekton.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			objs := []client.Object{installed.DeepCopy()}
+			for _, obj := range runs {
+				objs = append(objs, obj.DeepCopyObject().(client.Object))
+				if slices.Contains(tt.unreported, obj.GetName()) {
+					continue
+				}
+				objs = append(objs, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+					Name:      obj.GetName() + "-report",
+					Namespace: testNamespace,
+					Labels:    map[string]string{CertificationReportLabel: obj.GetName()},
+				}})
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+			p := pipeline.DeepCopy()
+			p.Spec.Retention = tt.policy
+			p.Spec.ReconcileMode = tt.mode
+			r := NewRetentionReconciler(c, logr.Discard(), scheme)
+			if _, err := r.Reconcile(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+
+			list := &tekton.PipelineRunList{}
+			if err := c.List(ctx, list); err != nil {
+				t.Fatal(err)
+			}
+			var kept []string
+			for _, pr := range list.Items {
+				kept = append(kept, pr.Name)
+			}
+			sort.Strings(kept)
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Fatalf("kept %v, want %v", kept, tt.wantKept)
+			}
+
+			if p.Status.Retention.PruneCandidates != tt.wantCandidates {
+				t.Fatalf("prune candidates %d, want %d", p.Status.Retention.PruneCandidates, tt.wantCandidates)
+			}
+
+			// The expiry is computed from a later clock, it is at most a few seconds off
+			after := r.RequeueAfter()
+			if after > tt.wantAfter+time.Second || after < tt.wantAfter-10*time.Second {
+				t.Fatalf("RequeueAfter() = %v, want about %v", after, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestPruneCandidatesNextExpiry(t *testing.T) {
+	now := time.Now()
+	run := func(name string, age time.Duration) *tekton.PipelineRun {
+		return &tekton.PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: tekton.PipelineRunStatus{PipelineRunStatusFields: tekton.PipelineRunStatusFields{
+				CompletionTime: &metav1.Time{Time: now.Add(-age)},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		ages        map[string]time.Duration
+		keep        *int32
+		maxAge      *metav1.Duration
+		wantPruned  []string
+		wantExpires time.Duration
+	}{
+		{
+			name:       "keep count only",
+			ages:       map[string]time.Duration{"a": time.Hour, "b": 2 * time.Hour, "c": 3 * time.Hour},
+			keep:       ptr.To[int32](2),
+			wantPruned: []string{"c"},
+		},
+		{
+			name:        "maximum age only",
+			ages:        map[string]time.Duration{"a": time.Hour, "b": 2 * time.Hour, "c": 5 * time.Hour},
+			maxAge:      &metav1.Duration{Duration: 4 * time.Hour},
+			wantPruned:  []string{"c"},
+			wantExpires: 2*time.Hour + time.Second,
+		},
+		{
+			name:        "maximum age prunes runs within the keep count",
+			ages:        map[string]time.Duration{"a": time.Hour, "b": 5 * time.Hour, "c": 6 * time.Hour},
+			keep:        ptr.To[int32](2),
+			maxAge:      &metav1.Duration{Duration: 4 * time.Hour},
+			wantPruned:  []string{"b", "c"},
+			wantExpires: 3*time.Hour + time.Second,
+		},
+		{
+			name:        "keep count prunes runs younger than the maximum age",
+			ages:        map[string]time.Duration{"a": time.Hour, "b": 2 * time.Hour, "c": 3 * time.Hour},
+			keep:        ptr.To[int32](1),
+			maxAge:      &metav1.Duration{Duration: 4 * time.Hour},
+			wantPruned:  []string{"b", "c"},
+			wantExpires: 3*time.Hour + time.Second,
+		},
+		{
+			name:       "nothing kept expires",
+			ages:       map[string]time.Duration{"a": 5 * time.Hour},
+			keep:       ptr.To[int32](0),
+			maxAge:     &metav1.Duration{Duration: 4 * time.Hour},
+			wantPruned: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs []*tekton.PipelineRun
+			for name, age := range tt.ages {
+				runs = append(runs, run(name, age))
+			}
+
+			pruned := pruneCandidates(runs, tt.keep, tt.maxAge, now)
+			var names []string
+			for _, pr := range pruned {
+				names = append(names, pr.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.wantPruned) {
+				t.Fatalf("pruned %v, want %v", names, tt.wantPruned)
+			}
+			if expires := nextExpiry(runs, pruned, tt.maxAge, now); expires != tt.wantExpires {
+				t.Fatalf("next expiry %v, want %v", expires, tt.wantExpires)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// defaultServiceAccountName is the service account OpenShift Pipelines creates in every namespace
+	defaultServiceAccountName    = "pipeline"
+	serviceAccountReadyCondition = "ServiceAccountReady"
+	// gitCredentialAnnotationPrefix marks the secrets Tekton's credential initializer uses for git, the value of the
+	// annotation is the host the credentials are for
+	gitCredentialAnnotationPrefix = "tekton.dev/git-"
+	defaultGitHost                = "github.com"
+)
+
+type ServiceAccountReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func NewServiceAccountReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *ServiceAccountReconciler {
+	return &ServiceAccountReconciler{
+		Client: client,
+		Log:    log,
+		Scheme: scheme,
+	}
+}
+
+// serviceAccountName returns the name of the service account the pipelines run as.
+func serviceAccountName(pipeline *v1alpha1.OperatorPipeline) string {
+	if len(pipeline.Spec.ServiceAccountName) > 0 {
+		return pipeline.Spec.ServiceAccountName
+	}
+	return defaultServiceAccountName
+}
+
+// gitHost returns the host the git credential secrets authenticate to.
+func gitHost(pipeline *v1alpha1.OperatorPipeline) string {
+	if len(pipeline.Spec.GitHost) > 0 {
+		return pipeline.Spec.GitHost
+	}
+	return defaultGitHost
+}
+
+// Reconcile ensures the pipeline service account exists, pulls images with the docker registry secret and carries
+// the git credentials. An existing service account, such as the one created by OpenShift Pipelines, is only added
+// to, and only a service account created by the operator is owned by the OperatorPipeline. In ReportOnly mode
+// nothing is changed, the condition lists what would be.
+func (r *ServiceAccountReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	key := types.NamespacedName{Namespace: pipeline.Namespace, Name: serviceAccountName(pipeline)}
+	log := r.Log.WithValues("serviceaccount", key)
+
+	status := &v1alpha1.ServiceAccountStatus{Name: key.Name}
+	var problems []string
+
+	var pullSecrets []string
+	if len(pipeline.Spec.DockerRegistrySecretName) > 0 {
+		found, err := r.secretExists(ctx, pipeline.Namespace, pipeline.Spec.DockerRegistrySecretName)
+		if err != nil {
+			return true, err
+		}
+		if found {
+			pullSecrets = append(pullSecrets, pipeline.Spec.DockerRegistrySecretName)
+		} else {
+			problems = append(problems, fmt.Sprintf("docker registry secret %s not found", pipeline.Spec.DockerRegistrySecretName))
+		}
+	}
+
+	gitSecrets, gitProblems, pending, err := r.annotateGitCredentials(ctx, pipeline)
+	if err != nil {
+		return true, err
+	}
+	problems = append(problems, gitProblems...)
+
+	status.ImagePullSecrets = pullSecrets
+	status.GitCredentials = gitSecrets
+
+	if reportOnly(pipeline) {
+		linkPending, err := r.pendingLinks(ctx, key, pullSecrets, gitSecrets)
+		if err != nil {
+			return true, err
+		}
+		pending = append(pending, linkPending...)
+		pipeline.Status.ServiceAccount = status
+
+		if len(pending) > 0 {
+			problems = append(problems, "not changed in ReportOnly mode, would "+strings.Join(pending, ", "))
+		}
+		if len(problems) > 0 {
+			setPipelineCondition(pipeline, serviceAccountReadyCondition, false, "ReportOnly", strings.Join(problems, "; "))
+			return false, nil
+		}
+		setPipelineCondition(pipeline, serviceAccountReadyCondition, true, "AsExpected",
+			fmt.Sprintf("Service account %s is ready", key.Name))
+		return false, nil
+	}
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, sa, func() error {
+		if sa.CreationTimestamp.IsZero() {
+			if err := controllerutil.SetControllerReference(pipeline, sa, r.Scheme); err != nil {
+				return err
+			}
+		}
+		for _, name := range pullSecrets {
+			if !hasImagePullSecret(sa, name) {
+				sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+			}
+		}
+		// Images are pulled and git credentials initialized from the secrets the service account can mount
+		for _, name := range append(pullSecrets, gitSecrets...) {
+			if !hasMountableSecret(sa, name) {
+				sa.Secrets = append(sa.Secrets, corev1.ObjectReference{Name: name})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "could not ensure the pipeline service account")
+		setPipelineCondition(pipeline, serviceAccountReadyCondition, false, "Failed", err.Error())
+		return true, err
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Info(fmt.Sprintf("pipeline service account %s", result))
+	}
+
+	pipeline.Status.ServiceAccount = status
+
+	if len(problems) > 0 {
+		setPipelineCondition(pipeline, serviceAccountReadyCondition, false, "SecretsNotLinked", strings.Join(problems, "; "))
+		return false, nil
+	}
+	setPipelineCondition(pipeline, serviceAccountReadyCondition, true, "AsExpected",
+		fmt.Sprintf("Service account %s is ready", key.Name))
+	return false, nil
+}
+
+// annotateGitCredentials annotates the git credential secrets with the host they authenticate to, so Tekton's
+// credential initializer picks them up. Only basic-auth and ssh-auth secrets can be used by the initializer. The
+// GitHub SSH secret of the README is an opaque secret with an id_rsa key mounted as the ssh-dir workspace, it is
+// left alone, and so is the GitHub API token, which is not a git credential. Secrets already carrying a git
+// annotation are left as they are. In ReportOnly mode the annotations are returned as pending instead.
+func (r *ServiceAccountReconciler) annotateGitCredentials(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) ([]string, []string, []string, error) {
+	var names []string
+	for _, name := range []string{pipeline.Spec.GithubSSHSecretName, pipeline.Spec.GitCredentialsSecretName} {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	var linked, problems, pending []string
+	for _, name := range names {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: name}, secret)
+		if apierrors.IsNotFound(err) {
+			problems = append(problems, fmt.Sprintf("git secret %s not found", name))
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		var host string
+		switch {
+		case secret.Type == corev1.SecretTypeBasicAuth:
+			host = "https://" + gitHost(pipeline)
+		case secret.Type == corev1.SecretTypeSSHAuth:
+			host = gitHost(pipeline)
+		case name == pipeline.Spec.GithubSSHSecretName && len(secret.Data[defaultGithubSSHSecretKeyName]) > 0:
+			continue
+		default:
+			problems = append(problems, fmt.Sprintf("git secret %s is of type %s, Tekton's credential initializer needs %s or %s",
+				name, secret.Type, corev1.SecretTypeBasicAuth, corev1.SecretTypeSSHAuth))
+			continue
+		}
+
+		switch {
+		case hasGitCredentialAnnotation(secret):
+		case reportOnly(pipeline):
+			pending = append(pending, fmt.Sprintf("annotate secret %s for %s", name, host))
+		default:
+			patch := client.MergeFrom(secret.DeepCopy())
+			metav1.SetMetaDataAnnotation(&secret.ObjectMeta, gitCredentialAnnotationPrefix+"0", host)
+			if err := r.Patch(ctx, secret, patch); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		linked = append(linked, name)
+	}
+
+	sort.Strings(linked)
+	return linked, problems, pending, nil
+}
+
+// pendingLinks returns the changes the service account needs to pull images with and mount the secrets.
+func (r *ServiceAccountReconciler) pendingLinks(ctx context.Context, key types.NamespacedName, pullSecrets, gitSecrets []string) ([]string, error) {
+	sa := &corev1.ServiceAccount{}
+	err := r.Get(ctx, key, sa)
+	if apierrors.IsNotFound(err) {
+		return []string{fmt.Sprintf("create service account %s", key.Name)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, name := range pullSecrets {
+		if !hasImagePullSecret(sa, name) {
+			pending = append(pending, fmt.Sprintf("pull images with secret %s", name))
+		}
+	}
+	for _, name := range append(pullSecrets, gitSecrets...) {
+		if !hasMountableSecret(sa, name) {
+			pending = append(pending, fmt.Sprintf("link secret %s", name))
+		}
+	}
+	return pending, nil
+}
+
+func (r *ServiceAccountReconciler) secretExists(ctx context.Context, namespace, name string) (bool, error) {
+	err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &corev1.Secret{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func hasGitCredentialAnnotation(secret *corev1.Secret) bool {
+	for key := range secret.Annotations {
+		if strings.HasPrefix(key, gitCredentialAnnotationPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasImagePullSecret(sa *corev1.ServiceAccount, name string) bool {
+	for _, ref := range sa.ImagePullSecrets {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMountableSecret(sa *corev1.ServiceAccount, name string) bool {
+	for _, ref := range sa.Secrets {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,274 @@
+package reconcilers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "oco"
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func testSecret(name string, secretType corev1.SecretType, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Type:       secretType,
+		Data:       map[string][]byte{},
+	}
+	for key, value := range data {
+		secret.Data[key] = []byte(value)
+	}
+	return secret
+}
+
+func TestServiceAccountReconcilerGitCredentials(t *testing.T) {
+	// The secrets created by following the README and the optional pipeline configuration
+	readme := []client.Object{
+		testSecret("github-api-token", corev1.SecretTypeOpaque, map[string]string{"GITHUB_TOKEN": "token"}),
+		testSecret("github-ssh-credentials", corev1.SecretTypeOpaque, map[string]string{"id_rsa": "key"}),
+		testSecret("registry-dockerconfig-secret", corev1.SecretTypeDockerConfigJson, map[string]string{".dockerconfigjson": "{}"}),
+	}
+
+	tests := []struct {
+		name          string
+		spec          v1alpha1.OperatorPipelineSpec
+		secrets       []client.Object
+		wantReady     bool
+		wantLinked    []string
+		wantAnnotated []string
+	}{
+		{
+			name:      "README layout without optional secrets",
+			spec:      v1alpha1.OperatorPipelineSpec{},
+			secrets:   readme,
+			wantReady: true,
+		},
+		{
+			name: "README layout with the SSH and registry secrets",
+			spec: v1alpha1.OperatorPipelineSpec{
+				GitHubSecretName:         "github-api-token",
+				GithubSSHSecretName:      "github-ssh-credentials",
+				DockerRegistrySecretName: "registry-dockerconfig-secret",
+			},
+			secrets:    readme,
+			wantReady:  true,
+			wantLinked: []string{"registry-dockerconfig-secret"},
+		},
+		{
+			name: "ssh-auth and basic-auth secrets",
+			spec: v1alpha1.OperatorPipelineSpec{
+				GithubSSHSecretName:      "github-ssh-auth",
+				GitCredentialsSecretName: "github-basic-auth",
+			},
+			secrets: append([]client.Object{
+				testSecret("github-ssh-auth", corev1.SecretTypeSSHAuth, map[string]string{corev1.SSHAuthPrivateKey: "key"}),
+				testSecret("github-basic-auth", corev1.SecretTypeBasicAuth, map[string]string{"username": "bot", "password": "token"}),
+			}, readme...),
+			wantReady:     true,
+			wantLinked:    []string{"github-basic-auth", "github-ssh-auth"},
+			wantAnnotated: []string{"github-basic-auth", "github-ssh-auth"},
+		},
+		{
+			name:    "git credentials of an unusable type",
+			spec:    v1alpha1.OperatorPipelineSpec{GitCredentialsSecretName: "github-api-token"},
+			secrets: readme,
+		},
+		{
+			name:    "missing git credentials",
+			spec:    v1alpha1.OperatorPipelineSpec{GitCredentialsSecretName: "missing"},
+			secrets: readme,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := testScheme(t)
+			objs := make([]client.Object, 0, len(tt.secrets))
+			for _, obj := range tt.secrets {
+				objs = append(objs, obj.DeepCopyObject().(client.Object))
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+			pipeline := &v1alpha1.OperatorPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: testNamespace, UID: "uid"},
+				Spec:       tt.spec,
+			}
+			if _, err := NewServiceAccountReconciler(c, logr.Discard(), scheme).Reconcile(ctx, pipeline); err != nil {
+				t.Fatal(err)
+			}
+
+			condition := meta.FindStatusCondition(pipeline.Status.Conditions, serviceAccountReadyCondition)
+			if condition == nil || (condition.Status == metav1.ConditionTrue) != tt.wantReady {
+				t.Fatalf("condition %+v, want ready %v", condition, tt.wantReady)
+			}
+
+			sa := &corev1.ServiceAccount{}
+			if err := c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: defaultServiceAccountName}, sa); err != nil {
+				t.Fatal(err)
+			}
+			var linked []string
+			for _, ref := range sa.Secrets {
+				linked = append(linked, ref.Name)
+			}
+			if !reflect.DeepEqual(nameSet(linked), nameSet(tt.wantLinked)) {
+				t.Fatalf("service account secrets %v, want %v", linked, tt.wantLinked)
+			}
+
+			// The GitHub API token and the opaque SSH secret are never annotated
+			for _, obj := range tt.secrets {
+				secret := &corev1.Secret{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), secret); err != nil {
+					t.Fatal(err)
+				}
+				annotated := hasGitCredentialAnnotation(secret)
+				if want := nameSet(tt.wantAnnotated)[secret.Name]; annotated != want {
+					t.Errorf("secret %s annotated %v, want %v", secret.Name, annotated, want)
+				}
+			}
+		})
+	}
+}
+
+func nameSet(names []string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+func TestServiceAccountReconcilerReportOnly(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-basic-auth", Namespace: "operator-ci"},
+		Type:       corev1.SecretTypeBasicAuth,
+		Data:       map[string][]byte{"username": []byte("bot"), "password": []byte("token")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	pipeline := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci", UID: "uid"},
+		Spec: v1alpha1.OperatorPipelineSpec{
+			GitCredentialsSecretName: secret.Name,
+			ReconcileMode:            v1alpha1.ReconcileModeReportOnly,
+		},
+	}
+	if _, err := NewServiceAccountReconciler(c, logr.Discard(), scheme).Reconcile(ctx, pipeline); err != nil {
+		t.Fatal(err)
+	}
+
+	condition := meta.FindStatusCondition(pipeline.Status.Conditions, serviceAccountReadyCondition)
+	want := "not changed in ReportOnly mode, would annotate secret github-basic-auth for https://github.com, create service account pipeline"
+	if condition == nil || condition.Reason != "ReportOnly" || condition.Message != want {
+		t.Fatalf("condition %+v, want ReportOnly: %s", condition, want)
+	}
+
+	err := c.Get(ctx, types.NamespacedName{Namespace: "operator-ci", Name: defaultServiceAccountName}, &corev1.ServiceAccount{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("service account created in ReportOnly mode: %v", err)
+	}
+	got := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(secret), got); err != nil {
+		t.Fatal(err)
+	}
+	if hasGitCredentialAnnotation(got) {
+		t.Fatalf("secret annotated in ReportOnly mode: %v", got.Annotations)
+	}
+}
+
+func TestServiceAccountReconcilerGitHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		gitHost    string
+		secretType corev1.SecretType
+		want       string
+	}{
+		{
+			name:       "basic-auth secret for github.com",
+			secretType: corev1.SecretTypeBasicAuth,
+			want:       "https://github.com",
+		},
+		{
+			name:       "ssh-auth secret for github.com",
+			secretType: corev1.SecretTypeSSHAuth,
+			want:       "github.com",
+		},
+		{
+			name:       "basic-auth secret for GitHub Enterprise",
+			gitHost:    "github.example.com",
+			secretType: corev1.SecretTypeBasicAuth,
+			want:       "https://github.example.com",
+		},
+		{
+			name:       "ssh-auth secret for GitHub Enterprise",
+			gitHost:    "github.example.com",
+			secretType: corev1.SecretTypeSSHAuth,
+			want:       "github.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "git-credentials", Namespace: "operator-ci"},
+				Type:       tt.secretType,
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+			pipeline := &v1alpha1.OperatorPipeline{
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci", UID: "uid"},
+				Spec:       v1alpha1.OperatorPipelineSpec{GitCredentialsSecretName: secret.Name, GitHost: tt.gitHost},
+			}
+			if _, err := NewServiceAccountReconciler(c, logr.Discard(), scheme).Reconcile(ctx, pipeline); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &corev1.Secret{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(secret), got); err != nil {
+				t.Fatal(err)
+			}
+			if host := got.Annotations[gitCredentialAnnotationPrefix+"0"]; host != tt.want {
+				t.Fatalf("secret annotated for %q, want %q", host, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package reconcilers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	pgpPublicKeyHeader  = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpSignatureHeader  = "-----BEGIN PGP SIGNATURE-----"
+	sshSignatureHeader  = "-----BEGIN SSH SIGNATURE-----"
+	sshSignatureMagic   = "SSHSIG"
+	sshSignatureGitName = "git"
+)
+
+// trustedKeys holds the public keys allowed to sign the operator-pipelines commits and tags.
+type trustedKeys struct {
+	// pgpKeyRing is the concatenation of all the armored PGP public keys
+	pgpKeyRing string
+	sshKeys    []ssh.PublicKey
+}
+
+// trustedKeysFromConfigMap reads the trusted keys from every entry of the ConfigMap. An entry can contain
+// armored PGP public keys or SSH public keys in authorized_keys format.
+func trustedKeysFromConfigMap(cm *corev1.ConfigMap) (*trustedKeys, error) {
+	keys := &trustedKeys{}
+	for name, data := range cm.Data {
+		if strings.Contains(data, pgpPublicKeyHeader) {
+			keys.pgpKeyRing += data + "\n"
+			continue
+		}
+
+		for rest := []byte(data); len(bytes.TrimSpace(rest)) > 0; {
+			key, _, _, next, err := ssh.ParseAuthorizedKey(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%s in ConfigMap %s does not contain valid public keys: %w", name, cm.Name, err)
+			}
+			keys.sshKeys = append(keys.sshKeys, key)
+			rest = next
+		}
+	}
+
+	if len(keys.pgpKeyRing) == 0 && len(keys.sshKeys) == 0 {
+		return nil, fmt.Errorf("ConfigMap %s does not contain any public keys", cm.Name)
+	}
+
+	return keys, nil
+}
+
+// verifyRelease verifies the signature of the resolved release. Annotated tags must carry a valid tag signature,
+// every other release must point to a commit with a valid signature. A tag that was since moved to another commit,
+// as can happen for a release from the history, no longer vouches for the resolved commit, so the commit itself
+// must be signed.
+func verifyRelease(r *git.Repository, resolved *v1alpha1.ResolvedRelease, keys *trustedKeys) error {
+	if resolved.Type == v1alpha1.ReleaseRefTag {
+		var tag *object.Tag
+		if ref, err := r.Reference(plumbing.NewTagReferenceName(resolved.Name), true); err == nil {
+			tag, _ = r.TagObject(ref.Hash())
+		}
+		if tag != nil && tag.Target.String() == resolved.Commit {
+			verifyPGP := func(keyRing string) error {
+				_, err := tag.Verify(keyRing)
+				return err
+			}
+			if err := keys.verify(tag.PGPSignature, tag.EncodeWithoutSignature, verifyPGP); err != nil {
+				return fmt.Errorf("%w: tag %s: %v", errors.ErrSignatureInvalid, resolved.Name, err)
+			}
+			return nil
+		}
+	}
+
+	commit, err := r.CommitObject(plumbing.NewHash(resolved.Commit))
+	if err != nil {
+		return err
+	}
+	verifyPGP := func(keyRing string) error {
+		_, err := commit.Verify(keyRing)
+		return err
+	}
+	if err := keys.verify(commit.PGPSignature, commit.EncodeWithoutSignature, verifyPGP); err != nil {
+		return fmt.Errorf("%w: commit %s: %v", errors.ErrSignatureInvalid, resolved.Commit, err)
+	}
+
+	return nil
+}
+
+// verify checks the signature of a commit or tag. PGP signatures are verified by go-git,
+// SSH signatures are verified following the SSHSIG protocol used by git.
+func (k *trustedKeys) verify(signature string, encode func(plumbing.EncodedObject) error, verifyPGP func(string) error) error {
+	switch {
+	case len(signature) == 0:
+		return fmt.Errorf("not signed")
+	case strings.HasPrefix(signature, pgpSignatureHeader):
+		if len(k.pgpKeyRing) == 0 {
+			return fmt.Errorf("signed with PGP but no trusted PGP keys are configured")
+		}
+		return verifyPGP(k.pgpKeyRing)
+	case strings.HasPrefix(signature, sshSignatureHeader):
+		encoded := &plumbing.MemoryObject{}
+		if err := encode(encoded); err != nil {
+			return err
+		}
+		reader, err := encoded.Reader()
+		if err != nil {
+			return err
+		}
+		message, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		return k.verifySSH(signature, message)
+	default:
+		return fmt.Errorf("unsupported signature format")
+	}
+}
+
+// sshSignature is the wire format of an SSH signature, without the leading magic preamble.
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is the wire format of the data an SSH signature is computed over, without the leading magic preamble.
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func (k *trustedKeys) verifySSH(armored string, message []byte) error {
+	block, _ := pem.Decode([]byte(armored))
+	if block == nil || !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		return fmt.Errorf("malformed SSH signature")
+	}
+
+	sig := sshSignature{}
+	if err := ssh.Unmarshal(block.Bytes[len(sshSignatureMagic):], &sig); err != nil {
+		return err
+	}
+	if sig.Version != 1 {
+		return fmt.Errorf("unsupported SSH signature version %d", sig.Version)
+	}
+	if sig.Namespace != sshSignatureGitName {
+		return fmt.Errorf("SSH signature namespace is %q, expected %q", sig.Namespace, sshSignatureGitName)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return err
+	}
+	if !k.trustsSSHKey(publicKey) {
+		return fmt.Errorf("signed with untrusted key %s", ssh.FingerprintSHA256(publicKey))
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported SSH signature hash algorithm %s", sig.HashAlgorithm)
+	}
+	h.Write(message)
+
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(sig.Signature, signature); err != nil {
+		return err
+	}
+
+	signed := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+
+	return publicKey.Verify(signed, signature)
+}
+
+func (k *trustedKeys) trustsSSHKey(key ssh.PublicKey) bool {
+	for _, trusted := range k.sshKeys {
+		if bytes.Equal(trusted.Marshal(), key.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
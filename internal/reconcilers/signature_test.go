@@ -0,0 +1,142 @@
+package reconcilers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+	operrors "github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The fixtures in testdata/signatures are commits signed by git with gpg.format=ssh, which runs ssh-keygen -Y sign
+// under the git namespace. commit-namespace carries the same payload signed under the file namespace.
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "signatures", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// storeCommit stores the raw commit object in an in-memory repository and returns the release resolving to it.
+func storeCommit(t *testing.T, r *git.Repository, raw string) *v1alpha1.ResolvedRelease {
+	t.Helper()
+
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.CommitObject)
+	w, err := obj.Writer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &v1alpha1.ResolvedRelease{Type: v1alpha1.ReleaseRefCommit, Name: hash.String(), Commit: hash.String()}
+}
+
+func TestVerifyReleaseSSH(t *testing.T) {
+	trusted := readFixture(t, "trusted.pub")
+	untrusted := readFixture(t, "untrusted.pub")
+	signed := readFixture(t, "commit")
+
+	tests := []struct {
+		name    string
+		keys    map[string]string
+		commit  string
+		wantErr string
+	}{
+		{
+			name:   "trusted key",
+			keys:   map[string]string{"maintainers": trusted},
+			commit: signed,
+		},
+		{
+			name:   "trusted key among several",
+			keys:   map[string]string{"maintainers": untrusted + trusted},
+			commit: signed,
+		},
+		{
+			name:    "wrong namespace",
+			keys:    map[string]string{"maintainers": trusted},
+			commit:  readFixture(t, "commit-namespace"),
+			wantErr: `namespace is "file"`,
+		},
+		{
+			name:    "wrong key",
+			keys:    map[string]string{"maintainers": trusted},
+			commit:  readFixture(t, "commit-untrusted"),
+			wantErr: "untrusted key",
+		},
+		{
+			name:    "tampered payload",
+			keys:    map[string]string{"maintainers": trusted},
+			commit:  strings.Replace(signed, "Release v1.0.0", "Release v1.0.1", 1),
+			wantErr: "signature did not verify",
+		},
+		{
+			name:    "not signed",
+			keys:    map[string]string{"maintainers": trusted},
+			commit:  "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nRelease v1.0.0\n",
+			wantErr: "not signed",
+		},
+		{
+			name:    "no trusted PGP keys",
+			keys:    map[string]string{"maintainers": trusted},
+			commit:  strings.Replace(signed, "SSH SIGNATURE", "PGP SIGNATURE", 2),
+			wantErr: "no trusted PGP keys",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := trustedKeysFromConfigMap(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "trusted-keys"}, Data: tt.keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := git.Init(memory.NewStorage(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = verifyRelease(r, storeCommit(t, r, tt.commit), keys)
+			switch {
+			case len(tt.wantErr) == 0 && err != nil:
+				t.Fatalf("verifyRelease: %v", err)
+			case len(tt.wantErr) == 0:
+			case err == nil:
+				t.Fatalf("verifyRelease succeeded, want an error containing %q", tt.wantErr)
+			case !errors.Is(err, operrors.ErrSignatureInvalid) || !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("verifyRelease: %v, want an invalid signature containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrustedKeysFromConfigMap(t *testing.T) {
+	for name, data := range map[string]map[string]string{
+		"empty":     {},
+		"malformed": {"maintainers": "ssh-ed25519 not-base64"},
+	} {
+		if _, err := trustedKeysFromConfigMap(&corev1.ConfigMap{Data: data}); err == nil {
+			t.Errorf("%s: trustedKeysFromConfigMap succeeded, want an error", name)
+		}
+	}
+}
@@ -3,25 +3,23 @@ package reconcilers
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"strings"
 
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/internal/errors"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/go-logr/logr"
 	imagev1 "github.com/openshift/api/image/v1"
-	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -40,13 +38,15 @@ type StatusReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	Source ManifestSource
 }
 
-func NewStatusReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *StatusReconciler {
+func NewStatusReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme, source ManifestSource) *StatusReconciler {
 	return &StatusReconciler{
 		Client: client,
 		Log:    log,
 		Scheme: scheme,
+		Source: source,
 	}
 }
 
@@ -70,9 +70,17 @@ func (r *StatusReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.Ope
 	// Even though defer evaluates the args here, this works since pipeline is a pointer
 	defer r.commitStatus(ctx, pipeline, log)
 
-	requeue, err = r.reconcilePipelineGitRepoStatus(ctx, pipeline)
+	if usesGitManifestSource(pipeline) {
+		requeue, err = r.reconcilePipelineGitRepoStatus(ctx, pipeline)
+		if requeue || err != nil {
+			log.Error(err, "pipelineGitRepoStatus")
+			return requeue, err
+		}
+	}
+
+	requeue, err = r.reconcileManifestSourceStatus(ctx, pipeline)
 	if requeue || err != nil {
-		log.Error(err, "pipelineGitRepoStatus")
+		log.Error(err, "manifestSourceStatus")
 		return requeue, err
 	}
 
@@ -113,21 +121,9 @@ func (r *StatusReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.Ope
 		}
 	}
 
-	requeue, err = r.reconcilePipelineStatus(ctx, pipeline, "CIPipeline", operatorCIPipelineYml, pipeline.Spec.ApplyCIPipeline)
+	requeue, err = r.reconcilePipelinesStatus(ctx, pipeline)
 	if requeue || err != nil {
-		log.Error(err, "ciPipelineStatus")
-		return requeue, err
-	}
-
-	requeue, err = r.reconcilePipelineStatus(ctx, pipeline, "HostedPipeline", operatorHostedPipelineYml, pipeline.Spec.ApplyHostedPipeline)
-	if requeue || err != nil {
-		log.Error(err, "hostedPipelineStatus")
-		return requeue, err
-	}
-
-	requeue, err = r.reconcilePipelineStatus(ctx, pipeline, "ReleasePipeline", operatorReleasePipelineYml, pipeline.Spec.ApplyReleasePipeline)
-	if requeue || err != nil {
-		log.Error(err, "releasePipelineStatus")
+		log.Error(err, "pipelinesStatus")
 		return requeue, err
 	}
 
@@ -283,110 +279,201 @@ func (r *StatusReconciler) reconcileSecretStatus(ctx context.Context, pipeline *
 
 func (r *StatusReconciler) reconcilePipelineGitRepoStatus(_ context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
 	readyCondition := metav1.Condition{
-		Type:               "GitRepoReady",
+		Type:               gitRepoReadyCondition,
 		ObservedGeneration: pipeline.Generation,
 		Status:             metav1.ConditionUnknown,
 	}
 
-	repo, err := git.PlainOpen(filepath.Join(os.Getenv("GIT_REPO_PATH"), "operator-pipeline"))
+	// The git reconciler already reported why the repository is not ready, keep its reason
+	if !meta.IsStatusConditionTrue(pipeline.Status.Conditions, gitRepoReadyCondition) {
+		return true, nil
+	}
+
+	_, err := pipelineCheckout(pipeline)
 	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"NotFound",
-			"Local repo unavailable",
+			fmt.Sprintf("Local checkout of %s unavailable", pipelinesRepoURL(pipeline)),
 			readyCondition))
 		return true, err
 	}
-	ref, err := repo.Head()
+
+	return false, nil
+}
+
+func (r *StatusReconciler) reconcileManifestSourceStatus(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	readyCondition := metav1.Condition{
+		Type:               "ManifestSourceReady",
+		ObservedGeneration: pipeline.Generation,
+		Status:             metav1.ConditionUnknown,
+	}
+
+	source := r.Source
+	manifests, err := source.Manifests(ctx, pipeline)
 	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
-			"Invalid",
-			"Local repo invalid",
+			"NotFound",
+			fmt.Sprintf("Manifests could not be read from %s: %v", source.Describe(pipeline), err),
 			readyCondition))
 		return true, err
 	}
 
-	pipeline.Status.PipelinesRepoHash = ref.Hash().String()
+	if _, err := fs.Stat(manifests, baseManifestsPath); err != nil {
+		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
+			r.conditionStatus(false),
+			"Invalid",
+			fmt.Sprintf("%s does not contain %s", source.Describe(pipeline), baseManifestsPath),
+			readyCondition))
+		return true, err
+	}
 
 	meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 		r.conditionStatus(true),
 		"AsExpected",
-		"Git repo is ready",
+		fmt.Sprintf("Manifests are read from %s", source.Describe(pipeline)),
 		readyCondition))
 
 	return false, nil
 }
 
-func (r *StatusReconciler) reconcilePipelineStatus(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, pipelineType, pipelineYaml string, pipelinePresent bool) (bool, error) {
+func (r *StatusReconciler) reconcilePipelinesStatus(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
 	readyCondition := metav1.Condition{
-		Type:               fmt.Sprintf("%sReady", pipelineType),
+		Type:               pipelinesReadyCondition,
 		ObservedGeneration: pipeline.Generation,
 		Status:             metav1.ConditionUnknown,
 	}
 
-	if !pipelinePresent {
+	if len(pipeline.Spec.Pipelines) == 0 {
+		pipeline.Status.Pipelines = nil
+		r.setLegacyPipelineConditions(pipeline, nil, nil)
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(true),
 			"AsExpected",
-			"Pipeline not requested",
+			"No pipelines requested",
 			readyCondition))
 		return false, nil
 	}
 
-	gitPath := filepath.Join(os.Getenv("GIT_REPO_PATH"), "operator-pipeline")
-	// This will check that the repo has been cloned and is valid
-	_, err := git.PlainOpen(gitPath)
+	// This will check that the manifests selected for the pipeline are available
+	manifests, err := r.Source.Manifests(ctx, pipeline)
 	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"NotFound",
-			"Local repo unavailable",
+			"Pipeline manifests unavailable",
 			readyCondition))
 		return true, err
 	}
 
-	fileName := filepath.Join(gitPath, pipelineManifestsPath, pipelineYaml)
-	b, err := os.ReadFile(fileName)
+	vars, err := templateVariables(manifests, pipeline)
 	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"Invalid",
-			"Pipeline YAML could not be read",
+			"Template variables not valid",
 			readyCondition))
 		return true, err
 	}
 
-	obj := new(tekton.Pipeline)
-	if err = yamlutil.Unmarshal(b, &obj); err != nil {
+	selected, err := selectPipelines(manifests, r.RESTMapper(), vars, pipeline)
+	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"Invalid",
-			"Pipeline YAML not valid",
+			"Pipelines YAML directory could not be read",
 			readyCondition))
 		return true, err
 	}
 
-	obj.SetNamespace(pipeline.Namespace)
-	err = r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj)
-	if err != nil {
+	statuses := make([]v1alpha1.SelectedPipelineStatus, 0, len(selected))
+	notReady := make([]string, 0, len(selected))
+	for _, s := range selected {
+		status := v1alpha1.SelectedPipelineStatus{
+			Name:         s.selection.Name,
+			PipelineName: s.pipelineName(),
+			Ready:        len(s.problem) == 0,
+			Message:      s.problem,
+		}
+
+		for _, obj := range s.objs {
+			if !status.Ready {
+				break
+			}
+			err = r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return true, err
+			}
+			if err != nil {
+				status.Ready = false
+				status.Message = fmt.Sprintf("%s %s not found", obj.GetKind(), obj.GetName())
+			}
+		}
+
+		if !status.Ready {
+			notReady = append(notReady, s.selection.Name)
+		}
+		statuses = append(statuses, status)
+	}
+	pipeline.Status.Pipelines = statuses
+	r.setLegacyPipelineConditions(pipeline, selected, statuses)
+
+	if len(notReady) > 0 {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"NotFound",
-			"Pipeline not found",
+			fmt.Sprintf("Pipelines not ready: %s", strings.Join(notReady, ", ")),
 			readyCondition))
-		return true, err
+		return true, nil
 	}
 
 	meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 		r.conditionStatus(true),
 		"AsExpected",
-		fmt.Sprintf("%s pipeline is ready", pipelineType),
+		fmt.Sprintf("%d pipelines are ready", len(statuses)),
 		readyCondition))
 
 	return false, nil
 }
 
+// setLegacyPipelineConditions keeps reporting the CIPipelineReady, HostedPipelineReady and ReleasePipelineReady
+// conditions for the clients that still watch them. They follow the selection of the pipeline file, whichever way
+// it was selected, and a pipeline that is not selected is reported ready as before.
+func (r *StatusReconciler) setLegacyPipelineConditions(pipeline *v1alpha1.OperatorPipeline, selected []selectedPipeline, statuses []v1alpha1.SelectedPipelineStatus) {
+	for _, legacy := range []struct {
+		pipelineType string
+		file         string
+	}{
+		{"CIPipeline", operatorCIPipelineYml},
+		{"HostedPipeline", operatorHostedPipelineYml},
+		{"ReleasePipeline", operatorReleasePipelineYml},
+	} {
+		readyCondition := metav1.Condition{
+			Type:               fmt.Sprintf("%sReady", legacy.pipelineType),
+			ObservedGeneration: pipeline.Generation,
+			Status:             metav1.ConditionUnknown,
+		}
+		condition := r.setStatusInfo(r.conditionStatus(true), "AsExpected", "Pipeline not requested", readyCondition)
+
+		for i, s := range selected {
+			if path.Base(s.fileName) != legacy.file {
+				continue
+			}
+			if statuses[i].Ready {
+				condition = r.setStatusInfo(r.conditionStatus(true), "AsExpected",
+					fmt.Sprintf("%s pipeline is ready", legacy.pipelineType), readyCondition)
+			} else {
+				condition = r.setStatusInfo(r.conditionStatus(false), "NotFound",
+					fmt.Sprintf("Pipeline not found: %s", statuses[i].Message), readyCondition)
+			}
+			break
+		}
+
+		meta.SetStatusCondition(&pipeline.Status.Conditions, condition)
+	}
+}
+
 func (r *StatusReconciler) reconcileTasksStatus(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
 	readyCondition := metav1.Condition{
 		Type:               "TasksReady",
@@ -394,20 +481,18 @@ func (r *StatusReconciler) reconcileTasksStatus(ctx context.Context, pipeline *v
 		Status:             metav1.ConditionUnknown,
 	}
 
-	gitPath := filepath.Join(os.Getenv("GIT_REPO_PATH"), "operator-pipeline")
-	// This will check that the repo has been cloned and is valid
-	_, err := git.PlainOpen(gitPath)
+	// This will check that the manifests selected for the pipeline are available
+	manifests, err := r.Source.Manifests(ctx, pipeline)
 	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"NotFound",
-			"Local repo unavailable",
+			"Pipeline manifests unavailable",
 			readyCondition))
 		return true, err
 	}
 
-	fileName := filepath.Join(gitPath, taskManifestsPath)
-	directory, err := os.ReadDir(fileName)
+	directory, err := fs.ReadDir(manifests, taskManifestsPath)
 	if err != nil {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
@@ -417,28 +502,73 @@ func (r *StatusReconciler) reconcileTasksStatus(ctx context.Context, pipeline *v
 		return true, err
 	}
 
-	obj := new(tekton.Task)
+	vars, err := templateVariables(manifests, pipeline)
+	if err != nil {
+		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
+			r.conditionStatus(false),
+			"Invalid",
+			"Template variables not valid",
+			readyCondition))
+		return true, err
+	}
+
+	selected, err := selectPipelines(manifests, r.RESTMapper(), vars, pipeline)
+	if err != nil {
+		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
+			r.conditionStatus(false),
+			"Invalid",
+			"Pipelines YAML directory could not be read",
+			readyCondition))
+		return true, err
+	}
+	var pipelines []*unstructured.Unstructured
+	for _, s := range selected {
+		pipelines = append(pipelines, s.objs...)
+	}
+
 	fileErrors := make([]string, 0, 10)
 	unmarshalErrors := make([]string, 0, 10)
 	getErrors := make([]string, 0, 10)
+	var tasks []*unstructured.Unstructured
 	for _, entry := range directory {
 		if entry.IsDir() {
 			continue
 		}
-		b, err := os.ReadFile(filepath.Join(fileName, entry.Name()))
+		b, err := fs.ReadFile(manifests, path.Join(taskManifestsPath, entry.Name()))
 		if err != nil {
 			fileErrors = append(fileErrors, entry.Name())
 			continue
 		}
-		if err = yamlutil.Unmarshal(b, &obj); err != nil {
+		objs, err := decodeManifests(b, entry.Name(), r.RESTMapper(), vars, pipeline.Namespace)
+		if err != nil {
 			unmarshalErrors = append(unmarshalErrors, entry.Name())
 			continue
 		}
-		obj.SetNamespace(pipeline.Namespace)
-		err = r.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj)
+		for _, obj := range objs {
+			if isTask(obj) {
+				tasks = append(tasks, obj)
+			}
+		}
+	}
+
+	// Only the Tasks the selected pipelines need are expected, like PipelineDependenciesReconciler applies them
+	selectedTasks, err := selectTasks(pipelines, tasks, pipeline.Spec.Tasks)
+	if err != nil {
+		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
+			r.conditionStatus(false),
+			"Invalid",
+			fmt.Sprintf("Tasks could not be selected: %v", err),
+			readyCondition))
+		return true, nil
+	}
+
+	for _, obj := range tasks {
+		if !selectedTasks[obj.GetName()] {
+			continue
+		}
+		err = r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
 		if err != nil && apierrors.IsNotFound(err) {
 			getErrors = append(getErrors, obj.GetName())
-			continue
 		}
 	}
 
@@ -446,7 +576,7 @@ func (r *StatusReconciler) reconcileTasksStatus(ctx context.Context, pipeline *v
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"NotFound",
-			fmt.Sprintf("Some tasks YAML files could not be read: %x", fileErrors),
+			fmt.Sprintf("Some tasks YAML files could not be read: %s", strings.Join(fileErrors, ", ")),
 			readyCondition))
 		return true, nil
 	}
@@ -455,16 +585,16 @@ func (r *StatusReconciler) reconcileTasksStatus(ctx context.Context, pipeline *v
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"Invalid",
-			fmt.Sprintf("Some tasks YAML files are not valid: %x", unmarshalErrors),
+			fmt.Sprintf("Some tasks YAML files are not valid: %s", strings.Join(unmarshalErrors, ", ")),
 			readyCondition))
 		return true, nil
 	}
 
-	if len(unmarshalErrors) > 0 {
+	if len(getErrors) > 0 {
 		meta.SetStatusCondition(&pipeline.Status.Conditions, r.setStatusInfo(
 			r.conditionStatus(false),
 			"NotFound",
-			fmt.Sprintf("Some tasks are not present: %x", getErrors),
+			fmt.Sprintf("Some tasks are not present: %s", strings.Join(getErrors, ", ")),
 			readyCondition))
 		return true, nil
 	}
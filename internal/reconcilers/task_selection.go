@@ -0,0 +1,74 @@
+package reconcilers
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// selectTasks returns the names of the Tasks to install: the ones the taskRefs of the pipelines refer to and the
+// ones matching an include glob of the spec, without the ones matching an exclude glob.
+func selectTasks(pipelines []*unstructured.Unstructured, tasks []*unstructured.Unstructured, selection *v1alpha1.TaskSelection) (map[string]bool, error) {
+	selected := map[string]bool{}
+	for _, obj := range pipelines {
+		if obj.GroupVersionKind() != tekton.SchemeGroupVersion.WithKind("Pipeline") {
+			continue
+		}
+		p := &tekton.Pipeline{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, p); err != nil {
+			return nil, fmt.Errorf("Pipeline %s: %w", obj.GetName(), err)
+		}
+		for _, name := range pipelineTaskRefs(p) {
+			selected[name] = true
+		}
+	}
+
+	if selection == nil {
+		return selected, nil
+	}
+
+	for _, task := range tasks {
+		included, err := matchesAny(selection.Include, task.GetName())
+		if err != nil {
+			return nil, err
+		}
+		if included {
+			selected[task.GetName()] = true
+		}
+	}
+
+	for name := range selected {
+		excluded, err := matchesAny(selection.Exclude, name)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			delete(selected, name)
+		}
+	}
+
+	return selected, nil
+}
+
+func matchesAny(globs []string, name string) (bool, error) {
+	for _, glob := range globs {
+		matched, err := path.Match(glob, name)
+		if err != nil {
+			return false, fmt.Errorf("task glob %q: %w", glob, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isTask returns whether the object is a Tekton Task.
+func isTask(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind() == tekton.SchemeGroupVersion.WithKind("Task")
+}
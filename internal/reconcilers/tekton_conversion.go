@@ -0,0 +1,71 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/pkg/apis"
+)
+
+const manifestsCurrentCondition = "ManifestsCurrent"
+
+// tektonDeprecationAnnotations are the annotations Tekton's conversion keeps the v1beta1 fields without a v1
+// equivalent in, so they survive a round trip. The resources key is not exported by Tekton.
+var tektonDeprecationAnnotations = []string{
+	v1beta1.TaskDeprecationsAnnotationKey,
+	"tekton.dev/v1beta1Resources",
+}
+
+// convertTektonManifest converts v1beta1 Pipelines and Tasks, still shipped by older operator-pipelines releases,
+// to v1 with Tekton's own conversion. Other objects are returned as they are.
+func convertTektonManifest(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if obj.GroupVersionKind().GroupVersion() != v1beta1.SchemeGroupVersion {
+		return obj, nil
+	}
+
+	var source apis.Convertible
+	var sink runtime.Object
+	switch obj.GetKind() {
+	case "Pipeline":
+		source, sink = &v1beta1.Pipeline{}, &tekton.Pipeline{}
+	case "Task":
+		source, sink = &v1beta1.Task{}, &tekton.Task{}
+	default:
+		return obj, nil
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, source); err != nil {
+		return nil, fmt.Errorf("decoding %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	if err := source.ConvertTo(context.Background(), sink.(apis.Convertible)); err != nil {
+		return nil, fmt.Errorf("converting %s %s to %s: %w", obj.GetKind(), obj.GetName(), tekton.SchemeGroupVersion, err)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(sink)
+	if err != nil {
+		return nil, err
+	}
+	converted := &unstructured.Unstructured{Object: content}
+	converted.SetGroupVersionKind(tekton.SchemeGroupVersion.WithKind(obj.GetKind()))
+	// The manifests carry no status or timestamps, the zero values of the typed object are left out
+	unstructured.RemoveNestedField(converted.Object, "status")
+	unstructured.RemoveNestedField(converted.Object, "metadata", "creationTimestamp")
+
+	return converted, nil
+}
+
+// usesDeprecatedTektonFields returns whether the conversion to v1 had to keep fields of the object that v1 no
+// longer has.
+func usesDeprecatedTektonFields(obj *unstructured.Unstructured) bool {
+	annotations := obj.GetAnnotations()
+	for _, key := range tektonDeprecationAnnotations {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
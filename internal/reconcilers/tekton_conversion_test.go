@@ -0,0 +1,167 @@
+package reconcilers
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestConvertTektonManifest(t *testing.T) {
+	tests := []struct {
+		name           string
+		manifest       string
+		want           string
+		wantDeprecated bool
+	}{
+		{
+			name: "v1beta1 Task",
+			manifest: `apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+  name: preflight
+spec:
+  params:
+  - name: bundle_path
+    default: .
+  steps:
+  - name: preflight
+    image: quay.io/opdev/preflight:stable
+    resources:
+      limits:
+        memory: 1Gi
+`,
+			want: `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: preflight
+spec:
+  params:
+  - name: bundle_path
+    default: .
+    type: string
+  steps:
+  - name: preflight
+    image: quay.io/opdev/preflight:stable
+    computeResources:
+      limits:
+        memory: 1Gi
+`,
+		},
+		{
+			name: "v1beta1 Pipeline",
+			manifest: `apiVersion: tekton.dev/v1beta1
+kind: Pipeline
+metadata:
+  name: operator-ci-pipeline
+spec:
+  workspaces:
+  - name: pipeline
+  tasks:
+  - name: preflight
+    taskRef:
+      name: preflight
+    workspaces:
+    - name: output
+      workspace: pipeline
+`,
+			want: `apiVersion: tekton.dev/v1
+kind: Pipeline
+metadata:
+  name: operator-ci-pipeline
+spec:
+  workspaces:
+  - name: pipeline
+  tasks:
+  - name: preflight
+    taskRef:
+      name: preflight
+    workspaces:
+    - name: output
+      workspace: pipeline
+`,
+		},
+		{
+			name: "v1beta1 Task with fields v1 no longer has",
+			manifest: `apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+  name: preflight
+spec:
+  steps:
+  - name: preflight
+    image: quay.io/opdev/preflight:stable
+    ports:
+    - containerPort: 8080
+`,
+			wantDeprecated: true,
+		},
+		{
+			name: "v1 Task",
+			manifest: `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: preflight
+spec:
+  steps:
+  - name: preflight
+    image: quay.io/opdev/preflight:stable
+`,
+			want: `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: preflight
+spec:
+  steps:
+  - name: preflight
+    image: quay.io/opdev/preflight:stable
+`,
+		},
+		{
+			name: "other kind",
+			manifest: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: pipelines-scc-clusterrole
+rules: []
+`,
+			want: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: pipelines-scc-clusterrole
+rules: []
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.manifest), &obj.Object); err != nil {
+				t.Fatal(err)
+			}
+			converted, err := convertTektonManifest(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deprecated := usesDeprecatedTektonFields(converted); deprecated != tt.wantDeprecated {
+				t.Fatalf("deprecated fields %v, want %v: %v", deprecated, tt.wantDeprecated, converted.GetAnnotations())
+			}
+			if tt.wantDeprecated {
+				if converted.GetAPIVersion() != "tekton.dev/v1" {
+					t.Fatalf("converted to %s", converted.GetAPIVersion())
+				}
+				return
+			}
+
+			want := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(converted.Object, want) {
+				got, _ := yaml.Marshal(converted.Object)
+				t.Fatalf("converted to\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,621 @@
+package reconcilers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	triggersReadyCondition = "TriggersReady"
+
+	defaultTriggersPipeline  = "operator-ci-pipeline"
+	defaultWebhookSecretName = "github-webhook-secret"
+	webhookSecretKey         = "webhook-secret"
+
+	eventListenerName   = "operator-ci-pipeline-listener"
+	triggerTemplateName = "operator-ci-pipeline-template"
+	// eventListenerPort is the name of the port of the service Tekton Triggers creates for the EventListener
+	eventListenerPort = "http-listener"
+
+	// eventListenerRolesName and eventListenerClusterRolesName are the ClusterRoles Tekton Triggers installs for
+	// the service accounts of EventListeners, granting the namespaced and the cluster-wide permissions they need
+	eventListenerRolesName        = "tekton-triggers-eventlistener-roles"
+	eventListenerClusterRolesName = "tekton-triggers-eventlistener-clusterroles"
+)
+
+var (
+	triggersGroupVersion = schema.GroupVersion{Group: "triggers.tekton.dev", Version: "v1beta1"}
+	routeGroupVersion    = schema.GroupVersion{Group: "route.openshift.io", Version: "v1"}
+
+	// triggerEvents are the events the triggers can be started by, in the order their triggers are declared
+	triggerEvents = []v1alpha1.TriggerEvent{v1alpha1.TriggerEventPush, v1alpha1.TriggerEventPullRequest}
+
+	// triggerBindingParams extract the repository and revision of the event from the GitHub webhook payload. Pull
+	// requests are built from the head of the pull request, which may be a fork.
+	triggerBindingParams = map[v1alpha1.TriggerEvent][]interface{}{
+		v1alpha1.TriggerEventPush: {
+			map[string]interface{}{"name": "git_repo_url", "value": "$(body.repository.clone_url)"},
+			map[string]interface{}{"name": "git_revision", "value": "$(body.after)"},
+		},
+		v1alpha1.TriggerEventPullRequest: {
+			map[string]interface{}{"name": "git_repo_url", "value": "$(body.pull_request.head.repo.clone_url)"},
+			map[string]interface{}{"name": "git_revision", "value": "$(body.pull_request.head.sha)"},
+		},
+	}
+)
+
+type TriggersReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func NewTriggersReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *TriggersReconciler {
+	return &TriggersReconciler{
+		Client: client,
+		Log:    log,
+		Scheme: scheme,
+	}
+}
+
+// Reconcile provisions the Tekton Triggers objects starting the pipeline on GitHub events: a TriggerBinding per
+// event, the TriggerTemplate creating the PipelineRun, the EventListener checking the webhook secret and the Route
+// exposing the listener. They are torn down when the triggers are removed from the spec. In ReportOnly mode they
+// are left as they are, the condition lists what would change.
+func (r *TriggersReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	if reportOnly(pipeline) {
+		return r.reportTriggers(ctx, pipeline)
+	}
+
+	settings := pipeline.Spec.Triggers
+	if settings == nil {
+		// Only pipelines that had triggers have something to tear down
+		if pipeline.Status.Triggers == nil {
+			meta.RemoveStatusCondition(&pipeline.Status.Conditions, triggersReadyCondition)
+			return false, nil
+		}
+		if err := r.teardown(ctx, pipeline, nil); err != nil {
+			r.Log.Error(err, "could not tear down the triggers")
+			return true, err
+		}
+		meta.RemoveStatusCondition(&pipeline.Status.Conditions, triggersReadyCondition)
+		pipeline.Status.Triggers = nil
+		return false, nil
+	}
+
+	events := settings.Events
+	if len(events) == 0 {
+		events = triggerEvents
+	}
+	secretName := overrideSecretFromSpec(defaultWebhookSecretName, settings.WebhookSecretName)
+	pipeline.Status.Triggers = &v1alpha1.TriggersStatus{WebhookSecretName: secretName}
+
+	if err := r.ensureWebhookSecret(ctx, pipeline, secretName); err != nil {
+		r.Log.Error(err, "could not ensure the webhook secret")
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "Failed", err.Error())
+		return true, err
+	}
+
+	target, err := r.triggersPipeline(ctx, pipeline, settings)
+	if target == nil || err != nil {
+		return true, err
+	}
+
+	objs := []*unstructured.Unstructured{triggerTemplate(pipeline, settings, target)}
+	for _, event := range events {
+		objs = append(objs, triggerBinding(pipeline, event))
+	}
+	if requeue, err := r.reconcileEventListenerServiceAccount(ctx, pipeline); requeue || err != nil {
+		return requeue, err
+	}
+
+	objs = append(objs, eventListener(pipeline, events, secretName), eventListenerRoute(pipeline))
+
+	for _, obj := range objs {
+		if err := controllerutil.SetControllerReference(pipeline, obj, r.Scheme); err != nil {
+			return true, err
+		}
+		err := r.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj), client.FieldOwner(FieldManager), client.ForceOwnership)
+		if meta.IsNoMatchError(err) {
+			setPipelineCondition(pipeline, triggersReadyCondition, false, "TriggersNotInstalled",
+				fmt.Sprintf("%s is not available, install Tekton Triggers: %v", obj.GroupVersionKind().GroupKind(), err))
+			return false, nil
+		}
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("could not apply %s %s", obj.GetKind(), obj.GetName()))
+			setPipelineCondition(pipeline, triggersReadyCondition, false, "Failed", err.Error())
+			return true, err
+		}
+	}
+
+	// Bindings of the events no longer selected are removed
+	if err := r.teardown(ctx, pipeline, events); err != nil {
+		return true, err
+	}
+
+	return r.reconcileTriggersStatus(ctx, pipeline)
+}
+
+// reconcileEventListenerServiceAccount provisions the service account of the EventListener and binds it to the
+// ClusterRoles Tekton Triggers installs for listeners. Binding them requires the operator to be allowed to bind
+// those ClusterRoles, the condition tells how to bind the service account by hand when it is not.
+func (r *TriggersReconciler) reconcileEventListenerServiceAccount(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	for _, name := range []string{eventListenerRolesName, eventListenerClusterRolesName} {
+		err := r.Get(ctx, types.NamespacedName{Name: name}, &rbacv1.ClusterRole{})
+		if apierrors.IsNotFound(err) {
+			setPipelineCondition(pipeline, triggersReadyCondition, false, "EventListenerRolesNotFound",
+				fmt.Sprintf("ClusterRole %s does not exist, install Tekton Triggers", name))
+			// The ClusterRoles are not watched, they are checked again later
+			return true, nil
+		}
+		if err != nil {
+			setPipelineCondition(pipeline, triggersReadyCondition, false, "Failed", err.Error())
+			return true, err
+		}
+	}
+
+	objs := []*unstructured.Unstructured{
+		eventListenerServiceAccount(pipeline),
+		eventListenerRoleBinding(pipeline),
+		eventListenerClusterRoleBinding(pipeline),
+	}
+	for _, obj := range objs {
+		// The ClusterRoleBinding cannot be owned by the OperatorPipeline, it is deleted with the last OperatorPipeline
+		// of the namespace like the other cluster role bindings
+		if len(obj.GetNamespace()) > 0 {
+			if err := controllerutil.SetControllerReference(pipeline, obj, r.Scheme); err != nil {
+				return true, err
+			}
+		}
+		err := r.Apply(ctx, client.ApplyConfigurationFromUnstructured(obj), client.FieldOwner(FieldManager), client.ForceOwnership)
+		if apierrors.IsForbidden(err) && r.boundByHand(ctx, obj) {
+			continue
+		}
+		if apierrors.IsForbidden(err) {
+			setPipelineCondition(pipeline, triggersReadyCondition, false, "EventListenerNotBound",
+				fmt.Sprintf("The operator is not allowed to create %s %s, bind service account %s to ClusterRole %s with a RoleBinding and to ClusterRole %s with a ClusterRoleBinding: %v",
+					obj.GetKind(), obj.GetName(), eventListenerName, eventListenerRolesName, eventListenerClusterRolesName, err))
+			return true, nil
+		}
+		if err != nil {
+			r.Log.Error(err, fmt.Sprintf("could not apply %s %s", obj.GetKind(), obj.GetName()))
+			setPipelineCondition(pipeline, triggersReadyCondition, false, "Failed", err.Error())
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// triggersPipeline returns the Pipeline the triggers start, nil when it is not installed yet.
+func (r *TriggersReconciler) triggersPipeline(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, settings *v1alpha1.TriggersSettings) (*tekton.Pipeline, error) {
+	name := settings.Pipeline
+	if len(name) == 0 {
+		name = defaultTriggersPipeline
+	}
+
+	target := &tekton.Pipeline{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: name}, target)
+	if apierrors.IsNotFound(err) {
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "PipelineNotFound",
+			fmt.Sprintf("Pipeline %s is not installed, the TriggerTemplate is made from the params and workspaces it declares", name))
+		return nil, nil
+	}
+	if err != nil {
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "Failed", err.Error())
+		return nil, err
+	}
+	return target, nil
+}
+
+// reportTriggers compares the triggers objects with the ones the spec asks for, without changing any of them.
+func (r *TriggersReconciler) reportTriggers(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	settings := pipeline.Spec.Triggers
+	if settings == nil {
+		if pipeline.Status.Triggers == nil {
+			meta.RemoveStatusCondition(&pipeline.Status.Conditions, triggersReadyCondition)
+			return false, nil
+		}
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "ReportOnly",
+			"Not changed in ReportOnly mode, would tear down the triggers")
+		return false, nil
+	}
+
+	events := settings.Events
+	if len(events) == 0 {
+		events = triggerEvents
+	}
+	secretName := overrideSecretFromSpec(defaultWebhookSecretName, settings.WebhookSecretName)
+	pipeline.Status.Triggers = &v1alpha1.TriggersStatus{WebhookSecretName: secretName}
+
+	var pending []string
+	err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: secretName}, &corev1.Secret{})
+	if apierrors.IsNotFound(err) {
+		pending = append(pending, fmt.Sprintf("generate webhook secret %s", secretName))
+	} else if err != nil {
+		return true, err
+	}
+
+	target, err := r.triggersPipeline(ctx, pipeline, settings)
+	if target == nil || err != nil {
+		return false, err
+	}
+
+	wanted := []*unstructured.Unstructured{triggerTemplate(pipeline, settings, target)}
+	for _, event := range events {
+		wanted = append(wanted, triggerBinding(pipeline, event))
+	}
+	wanted = append(wanted, eventListenerServiceAccount(pipeline), eventListenerRoleBinding(pipeline), eventListenerClusterRoleBinding(pipeline),
+		eventListener(pipeline, events, secretName), eventListenerRoute(pipeline))
+	selected := map[v1alpha1.TriggerEvent]bool{}
+	for _, event := range events {
+		selected[event] = true
+	}
+	var unwanted []*unstructured.Unstructured
+	for _, event := range triggerEvents {
+		if !selected[event] {
+			unwanted = append(unwanted, triggerBinding(pipeline, event))
+		}
+	}
+
+	for _, group := range []struct {
+		objs      []*unstructured.Unstructured
+		driftType v1alpha1.DriftType
+	}{{wanted, v1alpha1.DriftModified}, {unwanted, v1alpha1.DriftUnwanted}} {
+		for _, obj := range group.objs {
+			drift, err := objectDrift(ctx, r.Client, obj, group.driftType)
+			if meta.IsNoMatchError(err) {
+				setPipelineCondition(pipeline, triggersReadyCondition, false, "TriggersNotInstalled",
+					fmt.Sprintf("%s is not available, install Tekton Triggers: %v", obj.GroupVersionKind().GroupKind(), err))
+				return false, nil
+			}
+			if err != nil {
+				return true, err
+			}
+			if drift == nil {
+				continue
+			}
+			switch drift.Type {
+			case v1alpha1.DriftMissing:
+				pending = append(pending, fmt.Sprintf("create %s %s", drift.Kind, drift.Name))
+			case v1alpha1.DriftUnwanted:
+				pending = append(pending, fmt.Sprintf("delete %s %s", drift.Kind, drift.Name))
+			default:
+				pending = append(pending, fmt.Sprintf("update %s %s", drift.Kind, drift.Name))
+			}
+		}
+	}
+
+	if len(pending) > 0 {
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "ReportOnly",
+			fmt.Sprintf("Not changed in ReportOnly mode, would %s", strings.Join(pending, ", ")))
+		return false, nil
+	}
+	return r.reconcileTriggersStatus(ctx, pipeline)
+}
+
+// boundByHand returns whether the binding already exists with the role and subject the operator would give it,
+// as when it was created by hand because the operator is not allowed to bind the ClusterRoles.
+func (r *TriggersReconciler) boundByHand(ctx context.Context, obj *unstructured.Unstructured) bool {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return false
+	}
+	wantRole, _, _ := unstructured.NestedString(obj.Object, "roleRef", "name")
+	wantSubjects, _, _ := unstructured.NestedSlice(obj.Object, "subjects")
+	role, _, _ := unstructured.NestedString(existing.Object, "roleRef", "name")
+	subjects, _, _ := unstructured.NestedSlice(existing.Object, "subjects")
+	if len(wantSubjects) == 0 || role != wantRole {
+		return false
+	}
+	for _, subject := range subjects {
+		if equality.Semantic.DeepEqual(subject, wantSubjects[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileTriggersStatus reports the readiness of the EventListener and the URL of its Route.
+func (r *TriggersReconciler) reconcileTriggersStatus(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	listener := &unstructured.Unstructured{}
+	listener.SetGroupVersionKind(triggersGroupVersion.WithKind("EventListener"))
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: eventListenerName}, listener); err != nil {
+		return true, err
+	}
+	if ready, message := eventListenerReady(listener); !ready {
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "EventListenerNotReady", message)
+		// The EventListener is not watched, readiness is checked again later
+		return true, nil
+	}
+
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(routeGroupVersion.WithKind("Route"))
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: eventListenerName}, route); err != nil {
+		return true, err
+	}
+	host := admittedRouteHost(route)
+	if len(host) == 0 {
+		setPipelineCondition(pipeline, triggersReadyCondition, false, "RouteNotAdmitted",
+			fmt.Sprintf("Route %s has not been admitted", eventListenerName))
+		return true, nil
+	}
+
+	pipeline.Status.Triggers.WebhookURL = "https://" + host
+	setPipelineCondition(pipeline, triggersReadyCondition, true, "AsExpected",
+		fmt.Sprintf("Set %s as the webhook of the repository", pipeline.Status.Triggers.WebhookURL))
+	return false, nil
+}
+
+// ensureWebhookSecret generates the webhook secret when it does not exist. Secrets provided by the user are left
+// as they are.
+func (r *TriggersReconciler) ensureWebhookSecret(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, name string) error {
+	err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: name}, &corev1.Secret{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	token := make([]byte, 20)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: pipeline.Namespace},
+		StringData: map[string]string{webhookSecretKey: hex.EncodeToString(token)},
+	}
+	if err := controllerutil.SetControllerReference(pipeline, secret, r.Scheme); err != nil {
+		return err
+	}
+	r.Log.Info(fmt.Sprintf("generating webhook secret %s", name))
+	return r.Create(ctx, secret)
+}
+
+// teardown deletes the triggers objects. With events, only the bindings of the other events are deleted. The
+// webhook secret is only deleted when the operator generated it.
+func (r *TriggersReconciler) teardown(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, events []v1alpha1.TriggerEvent) error {
+	keep := map[v1alpha1.TriggerEvent]bool{}
+	for _, event := range events {
+		keep[event] = true
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, event := range triggerEvents {
+		if !keep[event] {
+			objs = append(objs, triggerBinding(pipeline, event))
+		}
+	}
+	if events == nil {
+		objs = append(objs, eventListenerRoute(pipeline), eventListener(pipeline, nil, ""), newTriggersObject(pipeline, triggersGroupVersion.WithKind("TriggerTemplate"), triggerTemplateName),
+			eventListenerClusterRoleBinding(pipeline), eventListenerRoleBinding(pipeline), eventListenerServiceAccount(pipeline))
+	}
+
+	for _, obj := range objs {
+		err := r.Delete(ctx, obj)
+		if err == nil {
+			r.Log.Info(fmt.Sprintf("deleted %s %s", obj.GetKind(), obj.GetName()))
+		}
+		// Without Tekton Triggers there is nothing to delete
+		if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return err
+		}
+	}
+
+	if events != nil || pipeline.Status.Triggers == nil {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: pipeline.Status.Triggers.WebhookSecretName}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if metav1.IsControlledBy(secret, pipeline) {
+		r.Log.Info(fmt.Sprintf("deleting webhook secret %s", secret.Name))
+		return client.IgnoreNotFound(r.Delete(ctx, secret))
+	}
+	return nil
+}
+
+func triggerBindingName(event v1alpha1.TriggerEvent) string {
+	return "operator-ci-pipeline-" + strings.ReplaceAll(string(event), "_", "-")
+}
+
+func triggerBinding(pipeline *v1alpha1.OperatorPipeline, event v1alpha1.TriggerEvent) *unstructured.Unstructured {
+	obj := newTriggersObject(pipeline, triggersGroupVersion.WithKind("TriggerBinding"), triggerBindingName(event))
+	obj.Object["spec"] = map[string]interface{}{"params": triggerBindingParams[event]}
+	return obj
+}
+
+// triggerTemplate returns the TriggerTemplate creating a PipelineRun of the pipeline, for the repository and
+// revision of the event. The run gets the params and workspaces of a CertificationRun of the same pipeline.
+func triggerTemplate(pipeline *v1alpha1.OperatorPipeline, settings *v1alpha1.TriggersSettings, target *tekton.Pipeline) *unstructured.Unstructured {
+	obj := newTriggersObject(pipeline, triggersGroupVersion.WithKind("TriggerTemplate"), triggerTemplateName)
+
+	values := pipelineRunValues(pipeline)
+	values["bundle_path"] = settings.BundlePath
+	for name, value := range settings.Params {
+		values[name] = value
+	}
+	values["git_repo_url"] = "$(tt.params.git_repo_url)"
+	values["git_revision"] = "$(tt.params.git_revision)"
+
+	run := &tekton.PipelineRun{
+		TypeMeta:   metav1.TypeMeta{APIVersion: tekton.SchemeGroupVersion.String(), Kind: "PipelineRun"},
+		ObjectMeta: metav1.ObjectMeta{GenerateName: target.Name + "-run-"},
+		Spec:       pipelineRunSpec(pipeline, target, values),
+	}
+
+	// The conversion cannot fail for a PipelineRun
+	resource, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(run)
+	delete(resource, "status")
+	unstructured.RemoveNestedField(resource, "metadata", "creationTimestamp")
+	workspaces, _, _ := unstructured.NestedSlice(resource, "spec", "workspaces")
+	for _, w := range workspaces {
+		if workspace, ok := w.(map[string]interface{}); ok {
+			unstructured.RemoveNestedField(workspace, "volumeClaimTemplate", "metadata")
+			unstructured.RemoveNestedField(workspace, "volumeClaimTemplate", "status")
+		}
+	}
+	_ = unstructured.SetNestedSlice(resource, workspaces, "spec", "workspaces")
+
+	obj.Object["spec"] = map[string]interface{}{
+		"params": []interface{}{
+			map[string]interface{}{"name": "git_repo_url"},
+			map[string]interface{}{"name": "git_revision"},
+		},
+		"resourcetemplates": []interface{}{resource},
+	}
+	return obj
+}
+
+// eventListener returns the EventListener with a trigger per event. The GitHub interceptor checks the webhook
+// secret and the event type.
+func eventListener(pipeline *v1alpha1.OperatorPipeline, events []v1alpha1.TriggerEvent, secretName string) *unstructured.Unstructured {
+	obj := newTriggersObject(pipeline, triggersGroupVersion.WithKind("EventListener"), eventListenerName)
+
+	triggers := []interface{}{}
+	for _, event := range events {
+		triggers = append(triggers, map[string]interface{}{
+			"name": string(event),
+			"interceptors": []interface{}{
+				map[string]interface{}{
+					"ref": map[string]interface{}{"name": "github"},
+					"params": []interface{}{
+						map[string]interface{}{
+							"name":  "secretRef",
+							"value": map[string]interface{}{"secretName": secretName, "secretKey": webhookSecretKey},
+						},
+						map[string]interface{}{"name": "eventTypes", "value": []interface{}{string(event)}},
+					},
+				},
+			},
+			"bindings": []interface{}{map[string]interface{}{"ref": triggerBindingName(event)}},
+			"template": map[string]interface{}{"ref": triggerTemplateName},
+		})
+	}
+
+	obj.Object["spec"] = map[string]interface{}{
+		"serviceAccountName": eventListenerName,
+		"triggers":           triggers,
+	}
+	return obj
+}
+
+// eventListenerServiceAccount returns the service account the EventListener runs with. It only reads the triggers
+// objects and creates the PipelineRuns, which run with the service account of the pipeline.
+func eventListenerServiceAccount(pipeline *v1alpha1.OperatorPipeline) *unstructured.Unstructured {
+	return newTriggersObject(pipeline, corev1.SchemeGroupVersion.WithKind("ServiceAccount"), eventListenerName)
+}
+
+// eventListenerRoleBinding grants the service account of the EventListener the namespaced permissions of listeners.
+func eventListenerRoleBinding(pipeline *v1alpha1.OperatorPipeline) *unstructured.Unstructured {
+	obj := newTriggersObject(pipeline, rbacv1.SchemeGroupVersion.WithKind("RoleBinding"), eventListenerName)
+	obj.Object["roleRef"] = eventListenerRoleRef(eventListenerRolesName)
+	obj.Object["subjects"] = eventListenerSubjects(pipeline)
+	return obj
+}
+
+// eventListenerClusterRoleBinding grants the service account of the EventListener the cluster-wide permissions of
+// listeners, such as reading ClusterInterceptors. Its name and labels are per namespace.
+func eventListenerClusterRoleBinding(pipeline *v1alpha1.OperatorPipeline) *unstructured.Unstructured {
+	obj := newTriggersObject(pipeline, rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"), eventListenerName+"-"+pipeline.Namespace)
+	obj.SetNamespace("")
+	obj.SetLabels(map[string]string{ClusterResourceLabel: "true", NamespaceLabel: pipeline.Namespace})
+	obj.Object["roleRef"] = eventListenerRoleRef(eventListenerClusterRolesName)
+	obj.Object["subjects"] = eventListenerSubjects(pipeline)
+	return obj
+}
+
+func eventListenerRoleRef(clusterRole string) map[string]interface{} {
+	return map[string]interface{}{"apiGroup": rbacv1.GroupName, "kind": "ClusterRole", "name": clusterRole}
+}
+
+func eventListenerSubjects(pipeline *v1alpha1.OperatorPipeline) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"kind":      rbacv1.ServiceAccountKind,
+		"name":      eventListenerName,
+		"namespace": pipeline.Namespace,
+	}}
+}
+
+// eventListenerRoute returns the Route exposing the service Tekton Triggers creates for the EventListener.
+func eventListenerRoute(pipeline *v1alpha1.OperatorPipeline) *unstructured.Unstructured {
+	obj := newTriggersObject(pipeline, routeGroupVersion.WithKind("Route"), eventListenerName)
+	obj.Object["spec"] = map[string]interface{}{
+		"to":   map[string]interface{}{"kind": "Service", "name": "el-" + eventListenerName},
+		"port": map[string]interface{}{"targetPort": eventListenerPort},
+		"tls": map[string]interface{}{
+			"termination":                   "edge",
+			"insecureEdgeTerminationPolicy": "Redirect",
+		},
+	}
+	return obj
+}
+
+func newTriggersObject(pipeline *v1alpha1.OperatorPipeline, gvk schema.GroupVersionKind, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(pipeline.Namespace)
+	return obj
+}
+
+// eventListenerReady returns whether the Ready condition of the EventListener is true, and its message otherwise.
+func eventListenerReady(listener *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(listener.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == string(corev1.ConditionTrue) {
+			return true, ""
+		}
+		message, _ := condition["message"].(string)
+		return false, fmt.Sprintf("EventListener %s is not ready: %s", listener.GetName(), message)
+	}
+	return false, fmt.Sprintf("EventListener %s is not ready yet", listener.GetName())
+}
+
+// admittedRouteHost returns the host of the Route once a router admitted it.
+func admittedRouteHost(route *unstructured.Unstructured) string {
+	ingresses, _, _ := unstructured.NestedSlice(route.Object, "status", "ingress")
+	for _, i := range ingresses {
+		ingress, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(ingress, "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if ok && condition["type"] == "Admitted" && condition["status"] == string(corev1.ConditionTrue) {
+				host, _ := ingress["host"].(string)
+				return host
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+package reconcilers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestTriggersReconcilerEventListenerServiceAccount(t *testing.T) {
+	roles := []client.Object{
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: eventListenerRolesName}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: eventListenerClusterRolesName}},
+	}
+	// The bindings created by following the README when the operator is not allowed to bind the ClusterRoles
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: eventListenerName, Namespace: testNamespace}
+	byHand := append([]client.Object{
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: eventListenerName, Namespace: testNamespace},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: eventListenerRolesName},
+			Subjects:   []rbacv1.Subject{subject},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: eventListenerName + "-" + testNamespace},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: eventListenerClusterRolesName},
+			Subjects:   []rbacv1.Subject{subject},
+		},
+	}, roles...)
+
+	tests := []struct {
+		name        string
+		objs        []client.Object
+		forbidden   bool
+		wantRequeue bool
+		wantReason  string
+	}{
+		{
+			name: "Tekton Triggers ClusterRoles installed",
+			objs: roles,
+		},
+		{
+			name:        "operator not allowed to bind the ClusterRoles",
+			objs:        roles,
+			forbidden:   true,
+			wantRequeue: true,
+			wantReason:  "EventListenerNotBound",
+		},
+		{
+			name:      "service account bound by hand",
+			objs:      byHand,
+			forbidden: true,
+		},
+		{
+			name:        "Tekton Triggers ClusterRoles missing",
+			objs:        roles[:1],
+			wantRequeue: true,
+			wantReason:  "EventListenerRolesNotFound",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := testScheme(t)
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...)
+			if tt.forbidden {
+				builder = builder.WithInterceptorFuncs(interceptor.Funcs{Apply: forbidBindings})
+			}
+			c := builder.Build()
+
+			pipeline := &v1alpha1.OperatorPipeline{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "OperatorPipeline"},
+				ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: testNamespace, UID: "uid"},
+			}
+			requeue, err := NewTriggersReconciler(c, logr.Discard(), scheme).reconcileEventListenerServiceAccount(ctx, pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requeue != tt.wantRequeue {
+				t.Fatalf("requeue %v, want %v", requeue, tt.wantRequeue)
+			}
+			// The condition is only set when the service account cannot be bound
+			condition := meta.FindStatusCondition(pipeline.Status.Conditions, triggersReadyCondition)
+			if (condition == nil) != (len(tt.wantReason) == 0) || (condition != nil && condition.Reason != tt.wantReason) {
+				t.Fatalf("condition %+v, want reason %q", condition, tt.wantReason)
+			}
+			if len(tt.wantReason) > 0 {
+				return
+			}
+
+			if err := c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: eventListenerName}, &corev1.ServiceAccount{}); err != nil {
+				t.Fatalf("service account: %v", err)
+			}
+
+			rb := &rbacv1.RoleBinding{}
+			if err := c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: eventListenerName}, rb); err != nil {
+				t.Fatal(err)
+			}
+			if rb.RoleRef.Kind != "ClusterRole" || rb.RoleRef.Name != eventListenerRolesName || len(rb.Subjects) != 1 || rb.Subjects[0] != subject {
+				t.Fatalf("RoleBinding %+v %+v", rb.RoleRef, rb.Subjects)
+			}
+			if !tt.forbidden && !metav1.IsControlledBy(rb, pipeline) {
+				t.Fatal("RoleBinding is not controlled by the OperatorPipeline")
+			}
+
+			crb := &rbacv1.ClusterRoleBinding{}
+			if err := c.Get(ctx, types.NamespacedName{Name: eventListenerName + "-" + testNamespace}, crb); err != nil {
+				t.Fatal(err)
+			}
+			if crb.RoleRef.Name != eventListenerClusterRolesName || len(crb.Subjects) != 1 || crb.Subjects[0] != subject {
+				t.Fatalf("ClusterRoleBinding %+v %+v", crb.RoleRef, crb.Subjects)
+			}
+			// Deleted with the last OperatorPipeline of the namespace, only the bindings the operator made are labelled
+			if !tt.forbidden && (crb.Labels[ClusterResourceLabel] != "true" || crb.Labels[NamespaceLabel] != testNamespace) {
+				t.Fatalf("ClusterRoleBinding labels %v", crb.Labels)
+			}
+
+			listener := eventListener(pipeline, triggerEvents, defaultWebhookSecretName)
+			if name := listener.Object["spec"].(map[string]interface{})["serviceAccountName"]; name != eventListenerName {
+				t.Fatalf("EventListener service account %v, want %s", name, eventListenerName)
+			}
+		})
+	}
+}
+
+// forbidBindings rejects the bindings like the API server does when the operator is not allowed to bind the
+// ClusterRoles they reference.
+func forbidBindings(ctx context.Context, c client.WithWatch, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(b, &typeMeta); err != nil {
+		return err
+	}
+	if typeMeta.Kind == "RoleBinding" || typeMeta.Kind == "ClusterRoleBinding" {
+		return apierrors.NewForbidden(rbacv1.Resource(strings.ToLower(typeMeta.Kind)+"s"), "", errors.New("attempting to grant RBAC permissions not currently held"))
+	}
+	return c.Apply(ctx, obj, opts...)
+}
+
+func TestTriggersReconcilerReportOnly(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := tekton.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	installed := &tekton.Pipeline{ObjectMeta: metav1.ObjectMeta{Name: "operator-ci-pipeline", Namespace: "operator-ci"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(installed).Build()
+
+	pipeline := &v1alpha1.OperatorPipeline{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "OperatorPipeline"},
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci", UID: "uid"},
+		Spec: v1alpha1.OperatorPipelineSpec{
+			ReconcileMode: v1alpha1.ReconcileModeReportOnly,
+			Triggers:      &v1alpha1.TriggersSettings{},
+		},
+	}
+	if _, err := NewTriggersReconciler(c, logr.Discard(), scheme).Reconcile(ctx, pipeline); err != nil {
+		t.Fatal(err)
+	}
+
+	condition := meta.FindStatusCondition(pipeline.Status.Conditions, triggersReadyCondition)
+	if condition == nil || condition.Reason != "ReportOnly" {
+		t.Fatalf("condition %+v, want reason ReportOnly", condition)
+	}
+	for _, want := range []string{"generate webhook secret " + defaultWebhookSecretName, "create ServiceAccount " + eventListenerName} {
+		if !strings.Contains(condition.Message, want) {
+			t.Fatalf("condition message %q does not mention %q", condition.Message, want)
+		}
+	}
+
+	err := c.Get(ctx, types.NamespacedName{Namespace: "operator-ci", Name: defaultWebhookSecretName}, &corev1.Secret{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("webhook secret generated in ReportOnly mode: %v", err)
+	}
+	err = c.Get(ctx, types.NamespacedName{Namespace: "operator-ci", Name: eventListenerName}, &corev1.ServiceAccount{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("service account created in ReportOnly mode: %v", err)
+	}
+}
+
+func TestTriggerTemplate(t *testing.T) {
+	pipeline := &v1alpha1.OperatorPipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: "operator-ci"},
+		Spec: v1alpha1.OperatorPipelineSpec{
+			PyxisSecretName:          "pyxis",
+			GithubSSHSecretName:      "github-ssh-credentials",
+			DockerRegistrySecretName: "registry-dockerconfig-secret",
+		},
+	}
+	settings := &v1alpha1.TriggersSettings{
+		Pipeline:   "operator-ci-pipeline",
+		BundlePath: "operators/example/0.0.1",
+		Params:     map[string]string{"env": "prod", "undeclared": "dropped"},
+	}
+	target := &tekton.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-ci-pipeline", Namespace: "operator-ci"},
+		Spec: tekton.PipelineSpec{
+			Params: tekton.ParamSpecs{
+				{Name: "git_repo_url"},
+				{Name: "git_revision"},
+				{Name: "bundle_path"},
+				{Name: "env"},
+				{Name: "pyxis_api_key_secret_name"},
+				{Name: "github_token_secret_name"},
+			},
+			Workspaces: []tekton.PipelineWorkspaceDeclaration{
+				{Name: "pipeline"},
+				{Name: "kubeconfig"},
+				{Name: "ssh-dir", Optional: true},
+				{Name: "registry-credentials", Optional: true},
+			},
+		},
+	}
+
+	obj := triggerTemplate(pipeline, settings, target)
+	templates, _, err := unstructured.NestedSlice(obj.Object, "spec", "resourcetemplates")
+	if err != nil || len(templates) != 1 {
+		t.Fatalf("resource templates %v: %v", templates, err)
+	}
+	run := &tekton.PipelineRun{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(templates[0].(map[string]interface{}), run); err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]string{}
+	for _, param := range run.Spec.Params {
+		params[param.Name] = param.Value.StringVal
+	}
+	wantParams := map[string]string{
+		"git_repo_url":              "$(tt.params.git_repo_url)",
+		"git_revision":              "$(tt.params.git_revision)",
+		"bundle_path":               "operators/example/0.0.1",
+		"env":                       "prod",
+		"pyxis_api_key_secret_name": "pyxis",
+		"github_token_secret_name":  defaultGithubAPISecretName,
+	}
+	if !reflect.DeepEqual(params, wantParams) {
+		t.Fatalf("params %v, want %v", params, wantParams)
+	}
+
+	secrets := map[string]string{}
+	for _, workspace := range run.Spec.Workspaces {
+		switch {
+		case workspace.Secret != nil:
+			secrets[workspace.Name] = workspace.Secret.SecretName
+		case workspace.VolumeClaimTemplate != nil:
+			secrets[workspace.Name] = ""
+		default:
+			t.Fatalf("workspace %s is neither a secret nor a claim", workspace.Name)
+		}
+	}
+	wantSecrets := map[string]string{
+		"pipeline":             "",
+		"kubeconfig":           defaultKubeconfigSecretName,
+		"ssh-dir":              "github-ssh-credentials",
+		"registry-credentials": "registry-dockerconfig-secret",
+	}
+	if !reflect.DeepEqual(secrets, wantSecrets) {
+		t.Fatalf("workspaces %v, want %v", secrets, wantSecrets)
+	}
+	if run.Spec.TaskRunTemplate.ServiceAccountName != defaultServiceAccountName {
+		t.Fatalf("service account %q, want %q", run.Spec.TaskRunTemplate.ServiceAccountName, defaultServiceAccountName)
+	}
+}
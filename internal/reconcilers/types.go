@@ -2,10 +2,36 @@ package reconcilers
 
 import (
 	"context"
+	"time"
 
 	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Reconciler interface {
 	Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error)
 }
+
+// DelayedRequeuer is implemented by the reconcilers that need the OperatorPipeline reconciled again once some time
+// has passed, even though nothing they watch changes. RequeueAfter returns zero when no later reconcile is needed.
+type DelayedRequeuer interface {
+	RequeueAfter() time.Duration
+}
+
+// setPipelineCondition sets a condition owned by the reconciler that knows why it is not ready.
+// The status reconciler commits it along with the rest of the status.
+func setPipelineCondition(pipeline *v1alpha1.OperatorPipeline, conditionType string, ready bool, reason, message string) {
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		ObservedGeneration: pipeline.Generation,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,257 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	workspacesReadyCondition = "WorkspacesReady"
+	// workspaceTemplateConfigMapName holds the volume claim template, under the file name the operator-pipelines
+	// documentation uses for it
+	workspaceTemplateConfigMapName = "workspace-template"
+	workspaceTemplateKey           = "workspace-template.yaml"
+	defaultWorkspaceSize           = "5Gi"
+	defaultWorkspaceAccessMode     = corev1.ReadWriteOnce
+
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+type WorkspacesReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func NewWorkspacesReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme) *WorkspacesReconciler {
+	return &WorkspacesReconciler{
+		Client: client,
+		Log:    log,
+		Scheme: scheme,
+	}
+}
+
+// Reconcile publishes the volume claim template of the pipeline workspaces, when they are set, and checks their
+// storage class against the StorageClasses of the cluster. Without workspaces there is no condition. The template is published even when the storage class
+// cannot be used, the condition tells why its claims would not bind. A workspace-template ConfigMap the operator
+// did not create, such as one written by hand following the operator-pipelines documentation, is never adopted.
+// In ReportOnly mode the template is left as it is, the condition tells how it would change.
+func (r *WorkspacesReconciler) Reconcile(ctx context.Context, pipeline *v1alpha1.OperatorPipeline) (bool, error) {
+	log := r.Log.WithValues("configmap", workspaceTemplateConfigMapName)
+
+	cm := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: workspaceTemplateConfigMapName}, cm)
+	if err != nil && !apierrors.IsNotFound(err) {
+		setPipelineCondition(pipeline, workspacesReadyCondition, false, "Failed", err.Error())
+		return true, err
+	}
+	owned := err == nil && metav1.IsControlledBy(cm, pipeline)
+
+	settings := pipeline.Spec.Workspaces
+	if settings == nil {
+		// The template published before workspaces were unset goes away with them. Without workspaces the storage
+		// class does not matter, so the condition goes away too.
+		pipeline.Status.Workspaces = nil
+		if owned && reportOnly(pipeline) {
+			setPipelineCondition(pipeline, workspacesReadyCondition, false, "ReportOnly",
+				"Not changed in ReportOnly mode, would remove the workspace template")
+			return false, nil
+		}
+		if owned {
+			if err := r.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "could not remove the workspace template")
+				setPipelineCondition(pipeline, workspacesReadyCondition, false, "Failed", err.Error())
+				return true, err
+			}
+			log.Info("workspace template removed")
+		}
+		meta.RemoveStatusCondition(&pipeline.Status.Conditions, workspacesReadyCondition)
+		return false, nil
+	}
+
+	if err == nil && !owned {
+		pipeline.Status.Workspaces = nil
+		setPipelineCondition(pipeline, workspacesReadyCondition, false, "ConfigMapNotOwned",
+			fmt.Sprintf("ConfigMap %s already exists and is not managed by this OperatorPipeline, remove it to publish the workspace template",
+				workspaceTemplateConfigMapName))
+		return false, nil
+	}
+
+	template, err := workspaceTemplate(settings)
+	if err != nil {
+		setPipelineCondition(pipeline, workspacesReadyCondition, false, "Failed", err.Error())
+		return true, err
+	}
+
+	if reportOnly(pipeline) {
+		var pending string
+		if !owned || cm.Data[workspaceTemplateKey] != template {
+			pending = "publish the workspace template"
+		}
+		return r.reportStorageClass(ctx, pipeline, settings, pending)
+	}
+
+	cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: workspaceTemplateConfigMapName, Namespace: pipeline.Namespace}}
+	result, err := controllerutil.CreateOrPatch(ctx, r.Client, cm, func() error {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[workspaceTemplateKey] = template
+		return controllerutil.SetControllerReference(pipeline, cm, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "could not publish the workspace template")
+		setPipelineCondition(pipeline, workspacesReadyCondition, false, "Failed", err.Error())
+		return true, err
+	}
+	if result != controllerutil.OperationResultNone {
+		log.Info(fmt.Sprintf("workspace template %s", result))
+	}
+
+	return r.checkStorageClass(ctx, pipeline, settings)
+}
+
+// reportStorageClass checks the storage class of the workspaces against the StorageClasses of the cluster. The
+// pending change of the workspace template, left undone in ReportOnly mode, turns the condition false.
+func (r *WorkspacesReconciler) reportStorageClass(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, settings *v1alpha1.WorkspaceSettings, pending string) (bool, error) {
+	requeue, err := r.checkStorageClass(ctx, pipeline, settings)
+	if err != nil || len(pending) == 0 {
+		return requeue, err
+	}
+	message := fmt.Sprintf("Not changed in ReportOnly mode, would %s", pending)
+	if condition := meta.FindStatusCondition(pipeline.Status.Conditions, workspacesReadyCondition); condition != nil {
+		message += "; " + condition.Message
+	}
+	setPipelineCondition(pipeline, workspacesReadyCondition, false, "ReportOnly", message)
+	return requeue, nil
+}
+
+// checkStorageClass sets the condition according to the storage class the workspace claims would get.
+func (r *WorkspacesReconciler) checkStorageClass(ctx context.Context, pipeline *v1alpha1.OperatorPipeline, settings *v1alpha1.WorkspaceSettings) (bool, error) {
+	classes := &storagev1.StorageClassList{}
+	if err := r.List(ctx, classes); err != nil {
+		r.Log.Error(err, "could not list the storage classes")
+		setPipelineCondition(pipeline, workspacesReadyCondition, false, "Failed", err.Error())
+		return true, err
+	}
+
+	var defaults []string
+	var newestDefault *storagev1.StorageClass
+	found := false
+	for i, class := range classes.Items {
+		if isDefaultStorageClass(&class) {
+			defaults = append(defaults, class.Name)
+			if newestDefault == nil || newestDefault.CreationTimestamp.Before(&class.CreationTimestamp) {
+				newestDefault = &classes.Items[i]
+			}
+		}
+		if class.Name == settings.StorageClassName {
+			found = true
+		}
+	}
+	sort.Strings(defaults)
+
+	status := &v1alpha1.WorkspacesStatus{ConfigMapName: workspaceTemplateConfigMapName, StorageClassName: settings.StorageClassName}
+	pipeline.Status.Workspaces = status
+
+	if len(settings.StorageClassName) == 0 {
+		// The claims get the default storage class when they are created, the newest one when there are several
+		if len(defaults) == 0 {
+			setPipelineCondition(pipeline, workspacesReadyCondition, false, "NoDefaultStorageClass",
+				"The cluster has no default storage class, set one in workspaces.storageClassName")
+			return false, nil
+		}
+		status.StorageClassName = newestDefault.Name
+		status.DefaultStorageClass = true
+		if len(defaults) > 1 {
+			setPipelineCondition(pipeline, workspacesReadyCondition, true, "MultipleDefaultStorageClasses",
+				fmt.Sprintf("The cluster has several default storage classes: %s", strings.Join(defaults, ", ")))
+			return false, nil
+		}
+		setPipelineCondition(pipeline, workspacesReadyCondition, true, "AsExpected",
+			fmt.Sprintf("Workspaces use the default storage class %s", status.StorageClassName))
+		return false, nil
+	}
+
+	if !found {
+		setPipelineCondition(pipeline, workspacesReadyCondition, false, "StorageClassNotFound",
+			fmt.Sprintf("Storage class %s does not exist", settings.StorageClassName))
+		return false, nil
+	}
+
+	for _, name := range defaults {
+		if name == settings.StorageClassName {
+			status.DefaultStorageClass = true
+		}
+	}
+	if !status.DefaultStorageClass {
+		setPipelineCondition(pipeline, workspacesReadyCondition, true, "NotDefaultStorageClass",
+			fmt.Sprintf("Storage class %s is not the default storage class of the cluster", settings.StorageClassName))
+		return false, nil
+	}
+	setPipelineCondition(pipeline, workspacesReadyCondition, true, "AsExpected",
+		fmt.Sprintf("Workspaces use the default storage class %s", settings.StorageClassName))
+	return false, nil
+}
+
+// workspaceTemplate returns the volume claim template of the workspaces in YAML.
+func workspaceTemplate(settings *v1alpha1.WorkspaceSettings) (string, error) {
+	b, err := yaml.Marshal(struct {
+		Spec corev1.PersistentVolumeClaimSpec `json:"spec"`
+	}{Spec: workspaceClaimSpec(settings)})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// workspaceClaimSpec returns the spec of the claims of the workspaces. Without a storage class the claims get the
+// default one of the cluster.
+func workspaceClaimSpec(settings *v1alpha1.WorkspaceSettings) corev1.PersistentVolumeClaimSpec {
+	if settings == nil {
+		settings = &v1alpha1.WorkspaceSettings{}
+	}
+
+	size := resource.MustParse(defaultWorkspaceSize)
+	if settings.Size != nil {
+		size = *settings.Size
+	}
+	accessMode := defaultWorkspaceAccessMode
+	if len(settings.AccessMode) > 0 {
+		accessMode = settings.AccessMode
+	}
+
+	spec := corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+		Resources: corev1.VolumeResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+		},
+	}
+	if len(settings.StorageClassName) > 0 {
+		spec.StorageClassName = &settings.StorageClassName
+	}
+	return spec
+}
+
+func isDefaultStorageClass(class *storagev1.StorageClass) bool {
+	return class.Annotations[defaultStorageClassAnnotation] == "true" ||
+		class.Annotations[betaDefaultStorageClassAnnotation] == "true"
+}
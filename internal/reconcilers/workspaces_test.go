@@ -0,0 +1,150 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redhat-openshift-ecosystem/operator-certification-operator/api/v1alpha1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWorkspacesReconcilerTemplateOwnership(t *testing.T) {
+	pipeline := &v1alpha1.OperatorPipeline{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "OperatorPipeline"},
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-pipeline", Namespace: testNamespace, UID: "uid"},
+	}
+	handWritten := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: workspaceTemplateConfigMapName, Namespace: testNamespace},
+		Data:       map[string]string{workspaceTemplateKey: "spec: {}\n"},
+	}
+	published := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workspaceTemplateConfigMapName,
+			Namespace: testNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       "OperatorPipeline",
+				Name:       pipeline.Name,
+				UID:        pipeline.UID,
+				Controller: ptr.To(true),
+			}},
+		},
+		Data: map[string]string{workspaceTemplateKey: "spec: {}\n"},
+	}
+
+	tests := []struct {
+		name          string
+		workspaces    *v1alpha1.WorkspaceSettings
+		mode          v1alpha1.ReconcileMode
+		existing      *corev1.ConfigMap
+		wantReason    string
+		wantConfigMap bool
+		wantOwned     bool
+	}{
+		{
+			name: "workspaces not set",
+		},
+		{
+			name:          "workspaces not set leaves a hand-written template alone",
+			existing:      handWritten,
+			wantConfigMap: true,
+		},
+		{
+			name:     "workspaces unset removes the published template",
+			existing: published,
+		},
+		{
+			name:          "workspaces set publishes the template",
+			workspaces:    &v1alpha1.WorkspaceSettings{},
+			wantReason:    "NoDefaultStorageClass",
+			wantConfigMap: true,
+			wantOwned:     true,
+		},
+		{
+			name:          "workspaces set updates the published template",
+			workspaces:    &v1alpha1.WorkspaceSettings{},
+			existing:      published,
+			wantReason:    "NoDefaultStorageClass",
+			wantConfigMap: true,
+			wantOwned:     true,
+		},
+		{
+			name:       "ReportOnly does not publish the template",
+			workspaces: &v1alpha1.WorkspaceSettings{},
+			mode:       v1alpha1.ReconcileModeReportOnly,
+			wantReason: "ReportOnly",
+		},
+		{
+			name:          "ReportOnly does not remove the published template",
+			mode:          v1alpha1.ReconcileModeReportOnly,
+			existing:      published,
+			wantReason:    "ReportOnly",
+			wantConfigMap: true,
+			wantOwned:     true,
+		},
+		{
+			name:          "workspaces set refuses to adopt a hand-written template",
+			workspaces:    &v1alpha1.WorkspaceSettings{},
+			existing:      handWritten,
+			wantReason:    "ConfigMapNotOwned",
+			wantConfigMap: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := testScheme(t)
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			if tt.existing != nil {
+				builder = builder.WithObjects(tt.existing.DeepCopy())
+			}
+			c := builder.Build()
+
+			p := pipeline.DeepCopy()
+			p.Spec.Workspaces = tt.workspaces
+			p.Spec.ReconcileMode = tt.mode
+			// Left by a previous reconcile
+			setPipelineCondition(p, workspacesReadyCondition, false, "NoDefaultStorageClass", "The cluster has no default storage class")
+			if _, err := NewWorkspacesReconciler(c, logr.Discard(), scheme).Reconcile(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+
+			// Without workspaces there is no condition
+			condition := meta.FindStatusCondition(p.Status.Conditions, workspacesReadyCondition)
+			switch {
+			case len(tt.wantReason) == 0 && condition != nil:
+				t.Fatalf("condition %+v, want none", condition)
+			case len(tt.wantReason) > 0 && (condition == nil || condition.Reason != tt.wantReason):
+				t.Fatalf("condition %+v, want reason %s", condition, tt.wantReason)
+			}
+			if tt.workspaces == nil && p.Status.Workspaces != nil {
+				t.Fatalf("workspaces status %+v without workspaces", p.Status.Workspaces)
+			}
+
+			cm := &corev1.ConfigMap{}
+			err := c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: workspaceTemplateConfigMapName}, cm)
+			switch {
+			case !tt.wantConfigMap && !apierrors.IsNotFound(err):
+				t.Fatalf("get ConfigMap: %v, want not found", err)
+			case !tt.wantConfigMap:
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+			if owned := metav1.IsControlledBy(cm, p); owned != tt.wantOwned {
+				t.Fatalf("ConfigMap owned %v, want %v", owned, tt.wantOwned)
+			}
+			if !tt.wantOwned && cm.Data[workspaceTemplateKey] != handWritten.Data[workspaceTemplateKey] {
+				t.Fatalf("hand-written template changed to %q", cm.Data[workspaceTemplateKey])
+			}
+		})
+	}
+}